@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/scottwinkler/terraform-policymaker/policymaker"
+)
+
+/*
+generateMapping is the generate-mapping command, which builds the mapping of a provider version from
+its source code and writes it to the output directory, e.g. to refresh the embedded mappings with
+-output policymaker/mappings
+*/
+func generateMapping(args []string) error {
+	flags := flag.NewFlagSet("generate-mapping", flag.ExitOnError)
+	providerPtr := flags.String("provider", "", "the provider to generate the mapping of (e.g. aws)")
+	providerVersionPtr := flags.String("provider-version", "", "the provider version to generate the mapping of (e.g. 5.31.0)")
+	providerSourcePtr := flags.String("provider-source", "", "the path to a checkout or archive (.tar.gz, .zip, ...) of the provider source code, instead of downloading it from GitHub")
+	organizationPtr := flags.String("organization", "terraform-providers", "the github org to fetch provider from")
+	useCachePtr := flags.Bool("use-cache", true, "if no, then will redownload the provider from GitHub")
+	outputPtr := flags.String("output", ".", "the directory to write the mapping to")
+	flags.Parse(args)
+	provider := *providerPtr
+	providerVersion := *providerVersionPtr
+	providerSource := *providerSourcePtr
+
+	if provider == "" || providerVersion == "" {
+		return errors.New("generate-mapping needs the -provider and -provider-version the mapping is for")
+	}
+	providerParser := policymaker.NewProviderParser(*organizationPtr, provider, *useCachePtr)
+	providerParser.SetVersion(providerVersion)
+	if providerSource != "" {
+		source, err := policymaker.NewProviderSource(providerSource)
+		if err != nil {
+			return &policymaker.ProviderError{Provider: provider, Op: "open source", Err: err}
+		}
+		providerParser.Source = source
+	}
+	mapping, err := providerParser.GenerateMapping()
+	if err != nil {
+		return err
+	}
+	content, err := mapping.JSON()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(*outputPtr, policymaker.EmbeddedMappingFileName(provider, providerParser.Version))
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("######### Mapping created: %s (%d resources)\n", path, len(mapping.Resources))
+	if mapping.SourceCommit == "" {
+		fmt.Printf("######### Warning: the provider source is not a git checkout, so the mapping does not record the commit it was generated from\n")
+	}
+	return nil
+}
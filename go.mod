@@ -1,10 +1,11 @@
 module github.com/scottwinkler/terraform-policymaker
 
-go 1.12
+go 1.16
 
 require (
-	github.com/armon/circbuf v0.0.0-20190214190532-5111143e8da2
 	github.com/google/go-github v17.0.0+incompatible
 	github.com/hashicorp/go-getter v1.3.0
+	github.com/hashicorp/hcl/v2 v2.8.2
 	github.com/tidwall/gjson v1.3.2
+	github.com/zclconf/go-cty v1.2.0
 )
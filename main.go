@@ -1,27 +1,113 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/scottwinkler/terraform-policymaker/policymaker"
 )
 
+// stringsFlag is a flag that can be given more than once, collecting every value
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func main() {
-	providerPtr := flag.String("provider", "aws", "provider to fetch (e.g. aws)")
+	if len(os.Args) > 1 && os.Args[1] == "generate-mapping" {
+		if err := generateMapping(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "update-catalogue" {
+		if err := updateCatalogue(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	providerPtr := flag.String("provider", "", "the provider to generate a policy for (e.g. aws), instead of every provider in the configuration")
 	organizationPtr := flag.String("organization", "terraform-providers", "the github org to fetch provider from")
-	useCachePtr := flag.Bool("use-cache", true, "if no, then will redownload the provider from GitHub")
+	useCachePtr := flag.Bool("use-cache", true, "if no, then will redownload the provider from GitHub and generate the mapping from it, instead of using the cached or embedded one")
 	pathPtr := flag.String("path", "./test", "the path to your Terraform configuration code")
+	planFilePtr := flag.String("plan-file", "", "the path to an existing plan in JSON format (from terraform show -json), or - to read it from stdin")
+	flag.StringVar(planFilePtr, "plan", "", "shorthand for -plan-file")
+	staticPtr := flag.Bool("static", false, "if yes, then parse the configuration files instead of running terraform plan")
+	changeAwarePtr := flag.Bool("change-aware", false, "if yes, then only grant the actions needed for the changes in the plan")
+	compressActionsPtr := flag.Bool("compress-actions", false, "if yes, then collapse actions into wildcards that match no other actions")
+	actionCataloguePtr := flag.String("action-catalogue", "", "the path to a catalogue of known actions per service, instead of the embedded one")
+	verbosePtr := flag.Bool("verbose", false, "if yes, then show the output of terraform while it runs")
+	timeoutPtr := flag.Duration("timeout", 0, "how long each terraform command may run (e.g. 10m), or 0 for no limit")
+	workspacePtr := flag.String("workspace", "", "the terraform workspace to select before running terraform plan")
+	refreshPlanPtr := flag.Bool("refresh-plan", false, "if yes, then run terraform plan even if the cached plan is up to date")
+	providerVersionPtr := flag.String("provider-version", "", "the provider version to build the mapping from (e.g. 5.31.0), instead of the one in the lock file")
+	offlinePtr := flag.Bool("offline", false, "if yes, then fail instead of downloading the provider source when there is no cached or embedded mapping")
+	providerSourcePtr := flag.String("provider-source", "", "the path to a checkout or archive (.tar.gz, .zip, ...) of the provider source code, instead of downloading it from GitHub")
+	var varFiles, vars, initArgs, planArgs stringsFlag
+	flag.Var(&varFiles, "var-file", "a variables file to pass to terraform plan, relative to -path (can be repeated)")
+	flag.Var(&vars, "var", "a variable to pass to terraform plan, e.g. region=us-east-1 (can be repeated)")
+	flag.Var(&initArgs, "init-arg", "an extra argument for terraform init, e.g. -backend-config=prod.hcl (can be repeated)")
+	flag.Var(&planArgs, "plan-arg", "an extra argument for terraform plan, e.g. -refresh=false (can be repeated)")
 	flag.Parse()
 	provider := *providerPtr
 	organization := *organizationPtr
 	useCache := *useCachePtr
 	path := *pathPtr
+	planFile := *planFilePtr
+	static := *staticPtr
+	changeAware := *changeAwarePtr
+	compressActions := *compressActionsPtr
+	actionCatalogue := *actionCataloguePtr
+	verbose := *verbosePtr
+	timeout := *timeoutPtr
+	workspace := *workspacePtr
+	refreshPlan := *refreshPlanPtr
+	providerVersion := *providerVersionPtr
+	providerSource := *providerSourcePtr
+	offline := *offlinePtr
 
-	pm := policymaker.NewPolicyMaker(&policymaker.Options{
-		Provider:     provider,
-		Organization: organization,
-		UseCache:     useCache,
-		Path:         path,
+	pm, err := policymaker.NewPolicyMaker(&policymaker.Options{
+		Provider:            provider,
+		Organization:        organization,
+		UseCache:            useCache,
+		Path:                path,
+		PlanFile:            planFile,
+		Static:              static,
+		ChangeAware:         changeAware,
+		CompressActions:     compressActions,
+		ActionCataloguePath: actionCatalogue,
+		Verbose:             verbose,
+		CommandTimeout:      timeout,
+		Workspace:           workspace,
+		VarFiles:            varFiles,
+		Vars:                vars,
+		InitArgs:            initArgs,
+		PlanArgs:            planArgs,
+		RefreshPlan:         refreshPlan,
+		ProviderVersion:     providerVersion,
+		ProviderSource:      providerSource,
+		Offline:             offline,
 	})
-	pm.GeneratePolicyDocument()
+	if err == nil {
+		// interrupting stops terraform instead of leaving it running
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err = pm.GeneratePolicyDocument(ctx)
+		stop()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
 }
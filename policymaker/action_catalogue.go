@@ -0,0 +1,301 @@
+package policymaker
+
+import (
+	"context"
+	// embed is needed for the service reference snapshot compiled into the binary
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServiceReferenceURL is where AWS publishes its service authorization reference in JSON
+const ServiceReferenceURL = "https://servicereference.us-east-1.amazonaws.com/"
+
+/*
+This is a snapshot of the AWS service authorization reference. It only covers the services
+it lists, and every action of those services; actions of other services cannot be validated.
+The update-catalogue command replaces it with a snapshot of every service, see FetchActionCatalogue.
+*/
+//go:embed catalogue/aws_service_reference.json
+var awsServiceReference []byte
+
+/*
+ActionCatalogue lists the services, actions, resource types and condition keys known to exist,
+as documented in the AWS service authorization reference
+*/
+type ActionCatalogue struct {
+	Version  string                       `json:"version"`
+	Source   string                       `json:"source,omitempty"`
+	Services map[string]*ServiceReference `json:"services"`
+}
+
+// ServiceReference describes a single service prefix, e.g. s3
+type ServiceReference struct {
+	Name string `json:"name"`
+	// ResourceTypes maps each resource type to the format of its ARN
+	ResourceTypes map[string]string           `json:"resource_types"`
+	ConditionKeys []string                    `json:"condition_keys"`
+	Actions       map[string]*ActionReference `json:"actions"`
+}
+
+// ActionReference describes a single action of a service
+type ActionReference struct {
+	AccessLevel   string   `json:"access_level"`
+	ResourceTypes []string `json:"resource_types,omitempty"`
+	ConditionKeys []string `json:"condition_keys,omitempty"`
+}
+
+// ActionValidation is the result of validating a list of actions against a catalogue
+type ActionValidation struct {
+	CatalogueVersion string `json:"catalogue_version"`
+	// Unknown actions belong to a service in the catalogue, but do not exist
+	Unknown []string `json:"unknown"`
+	// Unverified actions belong to a service the catalogue does not cover
+	Unverified []string `json:"unverified"`
+}
+
+// UnverifiedServices returns the sorted services of the unverified actions, e.g. s3
+func (v *ActionValidation) UnverifiedServices() []string {
+	servicesSet := make(map[string]bool)
+	for _, action := range v.Unverified {
+		servicesSet[strings.Split(action, ":")[0]] = true
+	}
+	return sortedKeys(servicesSet)
+}
+
+// DefaultActionCatalogue returns the catalogue embedded in the binary
+func DefaultActionCatalogue() *ActionCatalogue {
+	catalogue, err := parseActionCatalogue(awsServiceReference)
+	if err != nil {
+		panic(fmt.Sprintf("embedded action catalogue is invalid: %s", err))
+	}
+	return catalogue
+}
+
+/*
+LoadActionCatalogue reads an action catalogue from a local JSON file. Besides the format of
+the embedded snapshot, a file that only maps each service to its action names, e.g.
+{"s3": ["GetObject", "PutObject"]}, is accepted as well.
+*/
+func LoadActionCatalogue(path string) (*ActionCatalogue, error) {
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseActionCatalogue(dat)
+}
+
+func parseActionCatalogue(dat []byte) (*ActionCatalogue, error) {
+	var header struct {
+		Services json.RawMessage `json:"services"`
+	}
+	if err := json.Unmarshal(dat, &header); err != nil {
+		return nil, err
+	}
+	catalogue := &ActionCatalogue{}
+	if header.Services != nil {
+		if err := json.Unmarshal(dat, catalogue); err != nil {
+			return nil, err
+		}
+		return catalogue, nil
+	}
+	var actionsMap map[string][]string
+	if err := json.Unmarshal(dat, &actionsMap); err != nil {
+		return nil, err
+	}
+	catalogue.Version = "unversioned"
+	catalogue.Services = make(map[string]*ServiceReference, len(actionsMap))
+	for service, names := range actionsMap {
+		reference := &ServiceReference{Actions: make(map[string]*ActionReference, len(names))}
+		for _, name := range names {
+			reference.Actions[name] = &ActionReference{}
+		}
+		catalogue.Services[service] = reference
+	}
+	return catalogue, nil
+}
+
+// Actions returns the sorted names of all the actions of a service, without the service prefix
+func (c *ActionCatalogue) Actions(service string) []string {
+	reference := c.Services[service]
+	if reference == nil {
+		return nil
+	}
+	names := make([]string, 0, len(reference.Actions))
+	for name := range reference.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Matching returns every action of the catalogue (e.g. ec2:DescribeInstances) that a pattern like ec2:Describe* matches
+func (c *ActionCatalogue) Matching(pattern string) []string {
+	parts := strings.SplitN(pattern, ":", 2)
+	if len(parts) < 2 {
+		return nil
+	}
+	prefix := strings.TrimSuffix(parts[1], "*")
+	var matches []string
+	for _, name := range c.Actions(parts[0]) {
+		if (strings.HasSuffix(parts[1], "*") && strings.HasPrefix(name, prefix)) || name == parts[1] {
+			matches = append(matches, parts[0]+":"+name)
+		}
+	}
+	return matches
+}
+
+// Validate checks that every action, or every wildcard, matches at least one action in the catalogue
+func (c *ActionCatalogue) Validate(actions []string) *ActionValidation {
+	validation := &ActionValidation{
+		CatalogueVersion: c.Version,
+		Unknown:          []string{},
+		Unverified:       []string{},
+	}
+	for _, action := range actions {
+		service := strings.Split(action, ":")[0]
+		if c.Services[service] == nil {
+			validation.Unverified = append(validation.Unverified, action)
+			continue
+		}
+		if len(c.Matching(action)) == 0 {
+			validation.Unknown = append(validation.Unknown, action)
+		}
+	}
+	sort.Strings(validation.Unknown)
+	sort.Strings(validation.Unverified)
+	return validation
+}
+
+/*
+These are the documents of the AWS service authorization reference in JSON. The index lists the url of
+each service, whose document lists its actions, resource types and condition keys.
+*/
+type serviceReferenceIndexEntry struct {
+	Service string `json:"service"`
+	URL     string `json:"url"`
+}
+
+type serviceReferenceDocument struct {
+	Name    string `json:"Name"`
+	Actions []struct {
+		Name                string   `json:"Name"`
+		ActionConditionKeys []string `json:"ActionConditionKeys"`
+		Annotations         struct {
+			Properties struct {
+				IsList                 bool `json:"IsList"`
+				IsPermissionManagement bool `json:"IsPermissionManagement"`
+				IsTaggingOnly          bool `json:"IsTaggingOnly"`
+				IsWrite                bool `json:"IsWrite"`
+			} `json:"Properties"`
+		} `json:"Annotations"`
+		Resources []struct {
+			Name string `json:"Name"`
+		} `json:"Resources"`
+	} `json:"Actions"`
+	Resources []struct {
+		Name       string   `json:"Name"`
+		ARNFormats []string `json:"ARNFormats"`
+	} `json:"Resources"`
+	ConditionKeys []struct {
+		Name string `json:"Name"`
+	} `json:"ConditionKeys"`
+}
+
+/*
+FetchActionCatalogue downloads every service of the AWS service authorization reference published at
+baseURL (ServiceReferenceURL) into a catalogue, whose version is the date it was fetched on and whose
+source is baseURL
+*/
+func FetchActionCatalogue(ctx context.Context, baseURL string) (*ActionCatalogue, error) {
+	var index []*serviceReferenceIndexEntry
+	if err := fetchJSON(ctx, baseURL, &index); err != nil {
+		return nil, err
+	}
+	if len(index) == 0 {
+		return nil, fmt.Errorf("%s does not list any services", baseURL)
+	}
+	catalogue := &ActionCatalogue{
+		Version:  time.Now().UTC().Format("2006-01-02"),
+		Source:   baseURL,
+		Services: make(map[string]*ServiceReference, len(index)),
+	}
+	for _, entry := range index {
+		document := &serviceReferenceDocument{}
+		if err := fetchJSON(ctx, entry.URL, document); err != nil {
+			return nil, err
+		}
+		catalogue.Services[entry.Service] = serviceReferenceFromDocument(entry.Service, document)
+	}
+	return catalogue, nil
+}
+
+// helper function that converts the document of a service to the format of the catalogue
+func serviceReferenceFromDocument(service string, document *serviceReferenceDocument) *ServiceReference {
+	reference := &ServiceReference{
+		Name:          service,
+		ResourceTypes: make(map[string]string, len(document.Resources)),
+		ConditionKeys: []string{},
+		Actions:       make(map[string]*ActionReference, len(document.Actions)),
+	}
+	for _, resource := range document.Resources {
+		arn := ""
+		if len(resource.ARNFormats) > 0 {
+			arn = resource.ARNFormats[0]
+		}
+		reference.ResourceTypes[resource.Name] = arn
+	}
+	for _, key := range document.ConditionKeys {
+		reference.ConditionKeys = append(reference.ConditionKeys, key.Name)
+	}
+	sort.Strings(reference.ConditionKeys)
+	for _, action := range document.Actions {
+		properties := action.Annotations.Properties
+		accessLevel := "Read"
+		switch {
+		case properties.IsPermissionManagement:
+			accessLevel = "Permissions management"
+		case properties.IsTaggingOnly:
+			accessLevel = "Tagging"
+		case properties.IsWrite:
+			accessLevel = "Write"
+		case properties.IsList:
+			accessLevel = "List"
+		}
+		actionReference := &ActionReference{AccessLevel: accessLevel}
+		for _, resource := range action.Resources {
+			actionReference.ResourceTypes = append(actionReference.ResourceTypes, resource.Name)
+		}
+		if len(action.ActionConditionKeys) > 0 {
+			actionReference.ConditionKeys = append([]string{}, action.ActionConditionKeys...)
+			sort.Strings(actionReference.ConditionKeys)
+		}
+		reference.Actions[action.Name] = actionReference
+	}
+	return reference
+}
+
+// helper function that downloads a JSON document into v
+func fetchJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding %s: %s", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package policymaker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFetchActionCatalogue(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"service": "sqs", "url": "%s/v1/sqs/sqs.json"}]`, server.URL)
+	})
+	mux.HandleFunc("/v1/sqs/sqs.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"Name": "sqs",
+			"Actions": [
+				{"Name": "CreateQueue", "ActionConditionKeys": ["aws:TagKeys", "aws:RequestTag/${TagKey}"], "Annotations": {"Properties": {"IsWrite": true}}, "Resources": [{"Name": "queue"}]},
+				{"Name": "ListQueues", "Annotations": {"Properties": {"IsList": true}}},
+				{"Name": "TagQueue", "Annotations": {"Properties": {"IsTaggingOnly": true, "IsWrite": true}}, "Resources": [{"Name": "queue"}]},
+				{"Name": "AddPermission", "Annotations": {"Properties": {"IsPermissionManagement": true, "IsWrite": true}}, "Resources": [{"Name": "queue"}]},
+				{"Name": "GetQueueUrl", "Annotations": {"Properties": {}}, "Resources": [{"Name": "queue"}]}
+			],
+			"Resources": [{"Name": "queue", "ARNFormats": ["arn:${Partition}:sqs:${Region}:${Account}:${QueueName}"]}],
+			"ConditionKeys": [{"Name": "aws:TagKeys"}, {"Name": "aws:RequestTag/${TagKey}"}]
+		}`)
+	})
+
+	catalogue, err := FetchActionCatalogue(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if catalogue.Source != server.URL || catalogue.Version == "" {
+		t.Errorf("got version %q from %q", catalogue.Version, catalogue.Source)
+	}
+	sqs := catalogue.Services["sqs"]
+	if sqs == nil {
+		t.Fatal("sqs is not in the catalogue")
+	}
+	if got, want := sqs.ResourceTypes["queue"], "arn:${Partition}:sqs:${Region}:${Account}:${QueueName}"; got != want {
+		t.Errorf("got ARN %q, want %q", got, want)
+	}
+	accessLevels := make(map[string]string)
+	for name, action := range sqs.Actions {
+		accessLevels[name] = action.AccessLevel
+	}
+	want := map[string]string{
+		"AddPermission": "Permissions management",
+		"CreateQueue":   "Write",
+		"GetQueueUrl":   "Read",
+		"ListQueues":    "List",
+		"TagQueue":      "Tagging",
+	}
+	if !reflect.DeepEqual(accessLevels, want) {
+		t.Errorf("got access levels %v, want %v", accessLevels, want)
+	}
+	if got, want := sqs.Actions["CreateQueue"], (&ActionReference{AccessLevel: "Write", ResourceTypes: []string{"queue"}, ConditionKeys: []string{"aws:RequestTag/${TagKey}", "aws:TagKeys"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if validation := catalogue.Validate([]string{"sqs:CreateQueue", "sqs:CreateQueues", "s3:GetObject"}); !reflect.DeepEqual(validation.Unknown, []string{"sqs:CreateQueues"}) {
+		t.Errorf("got unknown actions %v", validation.Unknown)
+	}
+}
+
+func TestFetchActionCatalogueFails(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	if _, err := FetchActionCatalogue(context.Background(), server.URL); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestDefaultActionCatalogue(t *testing.T) {
+	catalogue := DefaultActionCatalogue()
+	if catalogue.Version == "" || catalogue.Source == "" {
+		t.Errorf("the embedded catalogue does not record where it was taken from, got version %q from %q", catalogue.Version, catalogue.Source)
+	}
+	for prefix, service := range catalogue.Services {
+		if len(service.Actions) == 0 {
+			t.Errorf("%s has no actions", prefix)
+		}
+	}
+	var missing []string
+	for _, service := range []string{"ec2", "iam", "lambda", "s3"} {
+		if catalogue.Services[service] == nil {
+			missing = append(missing, service)
+		}
+	}
+	if len(missing) > 0 {
+		t.Skipf("action catalogue %s does not cover %v, refresh it with update-catalogue", catalogue.Version, missing)
+	}
+}
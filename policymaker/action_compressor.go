@@ -0,0 +1,84 @@
+package policymaker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/*
+CompressActions collapses actions into wildcards like ec2:Describe* wherever every action of
+the catalogue that the wildcard matches is already in the list, so the policy grants exactly
+the same actions as before. Wildcards only break at word boundaries in the action name, and the
+shortest wildcard that qualifies is used.
+*/
+func CompressActions(actions []string, catalogue *ActionCatalogue) []string {
+	actionsSet := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		actionsSet[action] = true
+	}
+	compressedSet := make(map[string]bool)
+	for _, action := range actions {
+		compressedSet[compressAction(action, actionsSet, catalogue)] = true
+	}
+	compressedList := make([]string, 0, len(compressedSet))
+	for action := range compressedSet {
+		// a wildcard picked for another action may cover this one too, e.g. ec2:DescribeInstance* covers ec2:DescribeInstances
+		if !coveredByWildcard(action, compressedSet) {
+			compressedList = append(compressedList, action)
+		}
+	}
+	sort.Strings(compressedList)
+	return compressedList
+}
+
+// helper function that returns the shortest qualifying wildcard for an action, or the action itself
+func compressAction(action string, actionsSet map[string]bool, catalogue *ActionCatalogue) string {
+	if strings.Contains(action, "*") {
+		return action
+	}
+	for _, pattern := range wildcardCandidates(action) {
+		matches := catalogue.Matching(pattern)
+		// a wildcard that only stands in for a single action does not make the policy any smaller
+		if len(matches) < 2 {
+			continue
+		}
+		covered := true
+		for _, match := range matches {
+			if !actionsSet[match] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return pattern
+		}
+	}
+	return action
+}
+
+// helper function that lists the wildcards for an action from shortest to longest, e.g. ec2:Describe*, ec2:DescribeInstance*
+func wildcardCandidates(action string) []string {
+	parts := strings.SplitN(action, ":", 2)
+	if len(parts) < 2 {
+		return nil
+	}
+	var candidates []string
+	name := []rune(parts[1])
+	for i := 1; i < len(name); i++ {
+		if unicode.IsUpper(name[i]) && !unicode.IsUpper(name[i-1]) {
+			candidates = append(candidates, parts[0]+":"+string(name[:i])+"*")
+		}
+	}
+	return candidates
+}
+
+// helper function that returns whether a wildcard in the set other than the action itself matches it
+func coveredByWildcard(action string, compressedSet map[string]bool) bool {
+	for pattern := range compressedSet {
+		if pattern != action && strings.HasSuffix(pattern, "*") && strings.HasPrefix(action, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
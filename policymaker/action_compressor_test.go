@@ -0,0 +1,86 @@
+package policymaker
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompressActions(t *testing.T) {
+	catalogue, err := parseActionCatalogue([]byte(`{
+		"ec2": ["DescribeImages", "DescribeInstances", "DescribeInstanceStatus", "DescribeVpcs", "RunInstances"],
+		"s3": ["GetObject", "GetObjectAcl", "PutObject"],
+		"sns": ["Publish"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		actions []string
+		want    []string
+	}{
+		{
+			"every matching action is granted",
+			[]string{"ec2:DescribeImages", "ec2:DescribeInstances", "ec2:DescribeInstanceStatus", "ec2:DescribeVpcs", "ec2:RunInstances"},
+			[]string{"ec2:Describe*", "ec2:RunInstances"},
+		},
+		{
+			"only a longer wildcard is covered",
+			[]string{"ec2:DescribeInstances", "ec2:DescribeInstanceStatus"},
+			[]string{"ec2:DescribeInstance*"},
+		},
+		{
+			"a missing action prevents the wildcard",
+			[]string{"s3:GetObject", "s3:PutObject"},
+			[]string{"s3:GetObject", "s3:PutObject"},
+		},
+		{
+			"a wildcard for a single action is not used",
+			[]string{"sns:Publish"},
+			[]string{"sns:Publish"},
+		},
+		{
+			"services the catalogue does not cover are kept",
+			[]string{"lambda:GetFunction", "lambda:GetFunctionConfiguration"},
+			[]string{"lambda:GetFunction", "lambda:GetFunctionConfiguration"},
+		},
+		{
+			"wildcards are kept and replace the actions they cover",
+			[]string{"s3:*", "s3:GetObject", "sns:Publish"},
+			[]string{"s3:*", "sns:Publish"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := CompressActions(c.actions, catalogue); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCompressActionsWithDefaultCatalogue(t *testing.T) {
+	catalogue := DefaultActionCatalogue()
+	cases := []struct {
+		pattern string
+		other   string
+	}{
+		// the example of the -compress-actions flag
+		{"ec2:Describe*", "ec2:RunInstances"},
+		{"sqs:Get*", "sqs:CreateQueue"},
+	}
+	for _, c := range cases {
+		t.Run(c.pattern, func(t *testing.T) {
+			matching := catalogue.Matching(c.pattern)
+			if len(matching) == 0 {
+				t.Skipf("action catalogue %s does not cover %s, refresh it with update-catalogue", catalogue.Version, c.pattern)
+			}
+			want := []string{c.pattern, c.other}
+			sort.Strings(want)
+			if got := CompressActions(append(matching, c.other), catalogue); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package policymaker
+
+import (
+	"sort"
+	"strings"
+)
+
+// sdkOperationSuffixes are appended by the AWS golang sdk to variants of the same API call
+var sdkOperationSuffixes = []string{"WithContext", "Pages"}
+
+/*
+sdkRequestSuffix is appended by the AWS golang sdk to the variant of an API call that returns the
+request instead of sending it, e.g. GetObjectRequest. Real operations end in Request as well, e.g.
+ec2:ModifySpotFleetRequest, so it is only removed when the catalogue tells them apart.
+*/
+const sdkRequestSuffix = "Request"
+
+// ActionRewrite records an SDK operation that did not translate one to one into an IAM action
+type ActionRewrite struct {
+	Operation string   `json:"operation"`
+	Actions   []string `json:"actions"`
+	Reason    string   `json:"reason"`
+}
+
+/*
+actionTranslator sits between the SDK operations found in the provider source code and the
+IAM actions written to the permissions map. Every operation that is rewritten or dropped is
+recorded so that the result can be audited.
+*/
+type actionTranslator struct {
+	idiosyncracies map[string][]string
+	catalogue      *ActionCatalogue
+	rewrites       map[string]ActionRewrite
+}
+
+func newActionTranslator(idiosyncracies map[string][]string, catalogue *ActionCatalogue) *actionTranslator {
+	return &actionTranslator{
+		idiosyncracies: idiosyncracies,
+		catalogue:      catalogue,
+		rewrites:       make(map[string]ActionRewrite),
+	}
+}
+
+/*
+Translate converts an SDK operation such as s3:GetBucketEncryptionWithContext into the
+IAM actions it requires. An empty result means the operation should be dropped.
+*/
+func (t *actionTranslator) Translate(operation string) []string {
+	if actions, ok := t.idiosyncracies[operation]; ok {
+		t.record(operation, actions, "idiosyncracy")
+		return actions
+	}
+	stripped := t.stripRequestSuffix(stripOperationSuffixes(operation))
+	if actions, ok := t.idiosyncracies[stripped]; ok {
+		t.record(operation, actions, "suffix,idiosyncracy")
+		return actions
+	}
+	if stripped != operation {
+		t.record(operation, []string{stripped}, "suffix")
+	}
+	return []string{stripped}
+}
+
+// Rewrites returns every rewrite performed so far, sorted by operation
+func (t *actionTranslator) Rewrites() []ActionRewrite {
+	rewrites := make([]ActionRewrite, 0, len(t.rewrites))
+	for _, rewrite := range t.rewrites {
+		rewrites = append(rewrites, rewrite)
+	}
+	sort.Slice(rewrites, func(i, j int) bool {
+		return rewrites[i].Operation < rewrites[j].Operation
+	})
+	return rewrites
+}
+
+func (t *actionTranslator) record(operation string, actions []string, reason string) {
+	if len(actions) == 0 {
+		reason += ",dropped"
+	}
+	t.rewrites[operation] = ActionRewrite{
+		Operation: operation,
+		Actions:   actions,
+		Reason:    reason,
+	}
+}
+
+/*
+helper function that removes the Request suffix from an operation when it is the variant of a call that
+returns the request: the catalogue knows the operation without the suffix, but not with it. When the
+catalogue does not cover the service the operation is left alone, unless the suffix is doubled as in
+ModifySpotFleetRequestRequest.
+*/
+func (t *actionTranslator) stripRequestSuffix(operation string) string {
+	if !strings.HasSuffix(operation, sdkRequestSuffix) || strings.HasSuffix(operation, ":"+sdkRequestSuffix) {
+		return operation
+	}
+	stripped := strings.TrimSuffix(operation, sdkRequestSuffix)
+	service := strings.Split(operation, ":")[0]
+	if t.catalogue == nil || t.catalogue.Services[service] == nil {
+		if strings.HasSuffix(stripped, sdkRequestSuffix) {
+			return stripped
+		}
+		return operation
+	}
+	if len(t.catalogue.Matching(operation)) == 0 && len(t.catalogue.Matching(stripped)) > 0 {
+		return stripped
+	}
+	return operation
+}
+
+// helper function that removes the paginator and context suffixes from an operation
+func stripOperationSuffixes(operation string) string {
+	for _, suffix := range sdkOperationSuffixes {
+		if strings.HasSuffix(operation, suffix) && !strings.HasSuffix(operation, ":"+suffix) {
+			operation = strings.TrimSuffix(operation, suffix)
+		}
+	}
+	return operation
+}
@@ -0,0 +1,55 @@
+package policymaker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestActionTranslator(t *testing.T) {
+	catalogue, err := parseActionCatalogue([]byte(`{
+		"ec2": ["CancelSpotFleetRequests", "DescribeSpotFleetRequests", "ModifySpotFleetRequest", "RunInstances"],
+		"s3": ["GetObject"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	translator := newActionTranslator(map[string][]string{
+		"s3:ListObjectsV2":      {"s3:ListBucket"},
+		"sqs:GetQueueUrl":       {},
+		"ec2:DescribeInstances": {"ec2:DescribeInstances", "ec2:DescribeInstanceStatus"},
+	}, catalogue)
+	cases := []struct {
+		operation string
+		want      []string
+	}{
+		{"ec2:RunInstances", []string{"ec2:RunInstances"}},
+		{"ec2:RunInstancesWithContext", []string{"ec2:RunInstances"}},
+		{"s3:GetObjectRequest", []string{"s3:GetObject"}},
+		{"ec2:ModifySpotFleetRequest", []string{"ec2:ModifySpotFleetRequest"}},
+		{"ec2:ModifySpotFleetRequestWithContext", []string{"ec2:ModifySpotFleetRequest"}},
+		{"ec2:ModifySpotFleetRequestRequest", []string{"ec2:ModifySpotFleetRequest"}},
+		{"ec2:CancelSpotFleetRequests", []string{"ec2:CancelSpotFleetRequests"}},
+		{"ec2:CancelSpotFleetRequestsRequest", []string{"ec2:CancelSpotFleetRequests"}},
+		{"ec2:DescribeSpotFleetRequestsPages", []string{"ec2:DescribeSpotFleetRequests"}},
+		// services the catalogue does not cover keep their Request suffix, unless it is doubled
+		{"lambda:InvokeRequest", []string{"lambda:InvokeRequest"}},
+		{"lambda:InvokeRequestRequest", []string{"lambda:InvokeRequest"}},
+		{"s3:ListObjectsV2PagesWithContext", []string{"s3:ListBucket"}},
+		{"ec2:DescribeInstances", []string{"ec2:DescribeInstances", "ec2:DescribeInstanceStatus"}},
+		{"sqs:GetQueueUrl", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.operation, func(t *testing.T) {
+			if got := translator.Translate(c.operation); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+	rewrite := translator.rewrites["ec2:ModifySpotFleetRequestWithContext"]
+	if rewrite.Reason != "suffix" || !reflect.DeepEqual(rewrite.Actions, []string{"ec2:ModifySpotFleetRequest"}) {
+		t.Errorf("got rewrite %+v", rewrite)
+	}
+	if _, ok := translator.rewrites["ec2:ModifySpotFleetRequest"]; ok {
+		t.Error("an operation that was not rewritten was recorded")
+	}
+}
@@ -0,0 +1,404 @@
+package policymaker
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+)
+
+const awsClientTypeName = "AWSClient"
+
+// awsSDKServicePaths are the import paths the service packages of aws-sdk-go and aws-sdk-go-v2 are under
+var awsSDKServicePaths = []string{"github.com/aws/aws-sdk-go/service/", "github.com/aws/aws-sdk-go-v2/service/"}
+
+/*
+Since version 4 the AWSClient has a connection per service package of the provider, e.g. S3Conn,
+or an accessor method like S3Conn(ctx) or S3Client(ctx) since version 5. This maps the services
+whose IAM service prefix is neither the lower case package name nor found in awsClientMap.
+*/
+var awsServicePackagePrefixes = map[string]string{
+	"amp":                          "aps",
+	"apigatewayv2":                 "apigateway",
+	"appautoscaling":               "application-autoscaling",
+	"appintegrations":              "app-integrations",
+	"cloudcontrol":                 "cloudformation",
+	"codestarconnections":          "codestar-connections",
+	"codestarnotifications":        "codestar-notifications",
+	"cognitoidentity":              "cognito-identity",
+	"cognitoidp":                   "cognito-idp",
+	"docdbelastic":                 "docdb-elastic",
+	"efs":                          "elasticfilesystem",
+	"elasticsearch":                "es",
+	"emr":                          "elasticmapreduce",
+	"emrcontainers":                "emr-containers",
+	"emrserverless":                "emr-serverless",
+	"keyspaces":                    "cassandra",
+	"lexmodels":                    "lex",
+	"lexv2models":                  "lex",
+	"licensemanager":               "license-manager",
+	"networkfirewall":              "network-firewall",
+	"opensearch":                   "es",
+	"opensearchserverless":         "aoss",
+	"pinpoint":                     "mobiletargeting",
+	"redshiftserverless":           "redshift-serverless",
+	"resourceexplorer2":            "resource-explorer-2",
+	"resourcegroups":               "resource-groups",
+	"route53recoverycontrolconfig": "route53-recovery-control-config",
+	"route53recoveryreadiness":     "route53-recovery-readiness",
+	"s3connuricleaningdisabled":    "s3",
+	"sesv2":                        "ses",
+	"sfn":                          "states",
+	"ssmcontacts":                  "ssm-contacts",
+	"ssmincidents":                 "ssm-incidents",
+	"ssoadmin":                     "sso",
+	"timestreamwrite":              "timestream",
+	"vpclattice":                   "vpc-lattice",
+}
+
+// awsClientNonServiceAccessors are accessors of the AWSClient that look like connections, but are not
+var awsClientNonServiceAccessors = map[string]bool{
+	"httpclient": true,
+}
+
+// awsPaginatorRegexp matches the paginator constructors of aws-sdk-go-v2, e.g. s3.NewListObjectsV2Paginator(conn, input)
+var awsPaginatorRegexp = regexp.MustCompile(`^New(\w+)Paginator$`)
+
+/*
+awsCallAnalyzer resolves every method call made on an *AWSClient connection, whether the
+connection is used directly, assigned to a variable, aliased or passed into a helper function.
+The connection is a field of the AWSClient, e.g. s3conn or S3Conn, or since version 5 of the
+provider returned by an accessor method, e.g. S3Conn(ctx) or S3Client(ctx). The operations it
+finds are SDK operations, e.g. s3:PutObject.
+*/
+type awsCallAnalyzer struct {
+	*callGraph
+	// clients maps a variable or parameter holding a connection to its IAM service prefix
+	clients map[types.Object]string
+	// awsClients holds the variables and parameters holding the *AWSClient itself
+	awsClients map[types.Object]bool
+	// sdkServices maps an aws-sdk-go import path to its IAM service prefix
+	sdkServices map[string]string
+}
+
+// newAWSCallAnalyzer type checks the package in a directory and resolves its connections
+func newAWSCallAnalyzer(dir string) (*awsCallAnalyzer, error) {
+	g, err := newCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	a := &awsCallAnalyzer{
+		callGraph:   g,
+		clients:     make(map[types.Object]string),
+		awsClients:  make(map[types.Object]bool),
+		sdkServices: make(map[string]string),
+	}
+	g.findOperations = a.findOperations
+	g.operationPhases = actionPhases
+	a.collectSDKServices()
+	a.resolveClients()
+	a.collectCalls()
+	return a, nil
+}
+
+/*
+The AWSClient struct declares each connection as e.g. `s3conn *s3.S3`, which tells us which
+IAM service prefix belongs to which SDK package. This lets us recognise helper function
+parameters such as `conn *s3.S3` even when they are never assigned from the AWSClient.
+*/
+func (a *awsCallAnalyzer) collectSDKServices() {
+	for _, file := range a.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok || spec.Name.Name != awsClientTypeName {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return false
+			}
+			for _, field := range st.Fields.List {
+				path := a.sdkImportPath(field.Type)
+				if path == "" {
+					continue
+				}
+				for _, name := range field.Names {
+					if serviceName := awsClientMap[name.Name]; serviceName != "" {
+						a.sdkServices[path] = serviceName
+					}
+				}
+			}
+			return false
+		})
+	}
+}
+
+// helper function that returns the import path of a type expression like *s3.S3
+func (a *awsCallAnalyzer) sdkImportPath(expr ast.Expr) string {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	pkgName, ok := a.info.Uses[ident].(*types.PkgName)
+	if !ok {
+		return ""
+	}
+	return pkgName.Imported().Path()
+}
+
+/*
+resolveClients finds every variable and parameter that holds a connection. Assignments,
+aliases and arguments passed to package functions are propagated until nothing changes.
+*/
+func (a *awsCallAnalyzer) resolveClients() {
+	for _, file := range a.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch f := n.(type) {
+			case *ast.FuncDecl:
+				a.resolveTypedParams(f.Type)
+			case *ast.FuncLit:
+				a.resolveTypedParams(f.Type)
+			}
+			return true
+		})
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, file := range a.files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch s := n.(type) {
+				case *ast.AssignStmt:
+					if len(s.Lhs) != len(s.Rhs) {
+						return true
+					}
+					for i, lhs := range s.Lhs {
+						if ident, ok := lhs.(*ast.Ident); ok {
+							changed = a.markClient(a.objectOf(ident), s.Rhs[i]) || changed
+							changed = a.markAWSClient(a.objectOf(ident), s.Rhs[i]) || changed
+						}
+					}
+				case *ast.ValueSpec:
+					if len(s.Names) != len(s.Values) {
+						return true
+					}
+					for i, ident := range s.Names {
+						changed = a.markClient(a.objectOf(ident), s.Values[i]) || changed
+						changed = a.markAWSClient(a.objectOf(ident), s.Values[i]) || changed
+					}
+				case *ast.CallExpr:
+					decl := a.decls[a.calledFunc(s)]
+					if decl == nil {
+						return true
+					}
+					params := paramIdents(decl.Type)
+					for i, arg := range s.Args {
+						if i < len(params) && params[i] != nil {
+							changed = a.markClient(a.objectOf(params[i]), arg) || changed
+							changed = a.markAWSClient(a.objectOf(params[i]), arg) || changed
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// helper function that records the parameters whose type is a connection, or the *AWSClient
+func (a *awsCallAnalyzer) resolveTypedParams(ft *ast.FuncType) {
+	for _, field := range ft.Params.List {
+		serviceName := a.sdkServices[a.sdkImportPath(field.Type)]
+		if serviceName == "" {
+			serviceName = a.sdkClientService(field.Type)
+		}
+		isAWSClient := isAWSClientType(field.Type)
+		if serviceName == "" && !isAWSClient {
+			continue
+		}
+		for _, name := range field.Names {
+			if obj := a.objectOf(name); obj != nil {
+				if isAWSClient {
+					a.awsClients[obj] = true
+				} else {
+					a.clients[obj] = serviceName
+				}
+			}
+		}
+	}
+}
+
+/*
+sdkClientService returns the IAM service prefix of an SDK client type, i.e. *s3.S3 of aws-sdk-go or
+*s3.Client of aws-sdk-go-v2, or an empty string if the type is not one. The AWSClient of version 4
+and later is declared in another package, so its fields cannot tell which package is which service.
+*/
+func (a *awsCallAnalyzer) sdkClientService(expr ast.Expr) string {
+	path := a.sdkImportPath(expr)
+	if path == "" {
+		return ""
+	}
+	typeName := expr.(*ast.StarExpr).X.(*ast.SelectorExpr).Sel.Name
+	for _, prefix := range awsSDKServicePaths {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		pkg := strings.TrimPrefix(path, prefix)
+		if typeName == "Client" || strings.EqualFold(typeName, pkg) {
+			return awsClientService(pkg + "conn")
+		}
+	}
+	return ""
+}
+
+// helper function that records obj as a connection if expr evaluates to one
+func (a *awsCallAnalyzer) markClient(obj types.Object, expr ast.Expr) bool {
+	if obj == nil || a.clients[obj] != "" {
+		return false
+	}
+	serviceName := a.serviceOf(expr)
+	if serviceName == "" {
+		return false
+	}
+	a.clients[obj] = serviceName
+	return true
+}
+
+// helper function that records obj as holding the *AWSClient if expr evaluates to it
+func (a *awsCallAnalyzer) markAWSClient(obj types.Object, expr ast.Expr) bool {
+	if obj == nil || a.awsClients[obj] || !a.isAWSClientExpr(expr) {
+		return false
+	}
+	a.awsClients[obj] = true
+	return true
+}
+
+/*
+serviceOf returns the IAM service prefix of the connection an expression evaluates to,
+or an empty string if the expression is not a connection
+*/
+func (a *awsCallAnalyzer) serviceOf(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return a.serviceOf(e.X)
+	case *ast.Ident:
+		return a.clients[a.objectOf(e)]
+	case *ast.SelectorExpr:
+		if sel := a.info.Selections[e]; sel != nil {
+			if sel.Kind() == types.FieldVal && isAWSClient(sel.Recv()) {
+				return awsClientMap[e.Sel.Name]
+			}
+			return ""
+		}
+		// fall back to the syntax for expressions the type checker could not resolve
+		if a.isAWSClientExpr(e.X) {
+			return awsClientService(e.Sel.Name)
+		}
+	case *ast.CallExpr:
+		// an accessor method of the AWSClient, e.g. meta.(*conns.AWSClient).S3Client(ctx)
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && a.isAWSClientExpr(sel.X) {
+			return awsClientService(sel.Sel.Name)
+		}
+	}
+	return ""
+}
+
+/*
+helper function that returns whether an expression evaluates to the *AWSClient: a type assertion
+like meta.(*conns.AWSClient), a variable holding one, or the Meta() of a plugin framework resource
+*/
+func (a *awsCallAnalyzer) isAWSClientExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return a.isAWSClientExpr(e.X)
+	case *ast.Ident:
+		return a.awsClients[a.objectOf(e)]
+	case *ast.TypeAssertExpr:
+		return isAWSClientType(e.Type)
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		return ok && sel.Sel.Name == "Meta" && len(e.Args) == 0
+	}
+	return false
+}
+
+// helper function that returns whether a type expression is *AWSClient or *conns.AWSClient
+func isAWSClientType(expr ast.Expr) bool {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	switch t := star.X.(type) {
+	case *ast.Ident:
+		return t.Name == awsClientTypeName
+	case *ast.SelectorExpr:
+		return t.Sel.Name == awsClientTypeName
+	}
+	return false
+}
+
+/*
+awsClientService returns the IAM service prefix of a connection of the AWSClient by the name of its
+field or accessor method, e.g. s3conn, S3Conn or S3Client, or an empty string if it is not a connection
+*/
+func awsClientService(name string) string {
+	if serviceName := awsClientMap[name]; serviceName != "" {
+		return serviceName
+	}
+	lower := strings.ToLower(name)
+	if serviceName := awsServicePackagePrefixes[lower]; serviceName != "" {
+		return serviceName
+	}
+	if awsClientNonServiceAccessors[lower] {
+		return ""
+	}
+	for _, suffix := range []string{"conn", "client"} {
+		service := strings.TrimSuffix(lower, suffix)
+		if service == lower || service == "" {
+			continue
+		}
+		if serviceName := awsClientMap[service+"conn"]; serviceName != "" {
+			return serviceName
+		}
+		if serviceName := awsServicePackagePrefixes[service]; serviceName != "" {
+			return serviceName
+		}
+		return service
+	}
+	return ""
+}
+
+// helper function that records the SDK operations invoked on a connection within a node
+func (a *awsCallAnalyzer) findOperations(node ast.Node, operations map[string]token.Pos) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				if serviceName := a.serviceOf(sel.X); serviceName != "" {
+					addOperation(operations, serviceName+":"+sel.Sel.Name, call.Pos())
+				} else if matches := awsPaginatorRegexp.FindStringSubmatch(sel.Sel.Name); matches != nil && len(call.Args) > 0 {
+					// the pages of aws-sdk-go-v2 are requested through a paginator that is passed the connection
+					if serviceName := a.serviceOf(call.Args[0]); serviceName != "" {
+						addOperation(operations, serviceName+":"+matches[1], call.Pos())
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func isAWSClient(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == awsClientTypeName
+}
@@ -0,0 +1,51 @@
+package policymaker
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAWSCallAnalyzer(t *testing.T) {
+	analyzer, err := newAWSCallAnalyzer(filepath.Join(awsFixtureSource, "aws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	operationsByFile := analyzer.OperationsByFile(isTerraformResourceFile)
+	got := make(map[string]map[Phase][]string)
+	for path, operationsByPhase := range operationsByFile {
+		got[filepath.Base(path)] = make(map[Phase][]string)
+		for phase, operations := range operationsByPhase {
+			for _, operation := range operations {
+				got[filepath.Base(path)][phase] = append(got[filepath.Base(path)][phase], fmt.Sprintf("%s:%d", operation.Name, operation.Position.Line))
+			}
+			sort.Strings(got[filepath.Base(path)][phase])
+		}
+	}
+
+	cases := []struct {
+		name  string
+		file  string
+		phase Phase
+		want  []string
+	}{
+		{"alias of a connection", "resource_aws_s3_bucket.go", PhaseCreate, []string{"s3:CreateBucket:20"}},
+		{"helper function and multi-line call", "resource_aws_s3_bucket.go", PhaseRead, []string{"s3:GetBucketEncryptionWithContext:27", "s3:HeadObject:26", "s3:ListObjectVersionsPages:32"}},
+		{"connection passed into a helper", "resource_aws_s3_bucket.go", PhaseUpdate, []string{"s3:PutBucketTagging:42"}},
+		{"call on the connection field", "resource_aws_s3_bucket.go", PhaseDelete, []string{"s3:DeleteBucket:47"}},
+		{"var declaration", "data_source_aws_ecr_repository.go", PhaseRead, []string{"ecr:DescribeRepositories:5"}},
+		{"data source without a schema literal", "data_source_aws_ami.go", PhaseRead, []string{"ec2:DescribeImages:5"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if operations := got[c.file][c.phase]; !reflect.DeepEqual(operations, c.want) {
+				t.Errorf("%s %s: got %v, want %v", c.file, c.phase, operations, c.want)
+			}
+		})
+	}
+	if _, ok := got["config.go"]; ok {
+		t.Error("config.go is not a resource file")
+	}
+}
@@ -0,0 +1,49 @@
+package policymaker
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/*
+AWSExtractor builds the mapping of terraform-provider-aws from the calls its resources make on
+the AWS SDK, translated into IAM actions like s3:PutObject
+*/
+type AWSExtractor struct {
+	translator *actionTranslator
+}
+
+// NewAWSExtractor is the Constructor for AWSExtractor
+func NewAWSExtractor() *AWSExtractor {
+	return &AWSExtractor{}
+}
+
+/*
+Extract builds the mapping of the provider source in sourceDir. The resources are found by how the
+provider registers them, so that the internal/service/<service> layout of version 4 and later works,
+or else by their file names.
+*/
+func (e *AWSExtractor) Extract(sourceDir string) (*Mapping, error) {
+	e.translator = newActionTranslator(awsIdiosyncracyActionMap, DefaultActionCatalogue())
+	extractor := &packageExtractor{
+		isResourceFile: isTerraformResourceFile,
+		// the files are named after the resource type already, e.g. resource_aws_instance.go
+		resourceName: func(path string) string {
+			return strings.TrimSuffix(filepath.Base(path), ".go")
+		},
+		newAnalyzer: func(dir string) (callAnalyzer, error) {
+			return newAWSCallAnalyzer(dir)
+		},
+		translate:     e.translator.Translate,
+		registrations: collectAWSRegistrations,
+	}
+	return extractor.extract(sourceDir)
+}
+
+// Rewrites returns the SDK operations the last Extract did not translate one to one into IAM actions
+func (e *AWSExtractor) Rewrites() []ActionRewrite {
+	if e.translator == nil {
+		return nil
+	}
+	return e.translator.Rewrites()
+}
@@ -0,0 +1,112 @@
+package policymaker
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollectAWSRegistrations(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   map[string]string
+	}{
+		{
+			"service packages of version 5",
+			"testdata/providers/terraform-provider-aws-v5",
+			map[string]string{
+				"data_source_aws_ami":                 "internal/service/ec2 DataSourceAMI",
+				"data_source_aws_iam_policy_document": "internal/service/iam DataSourcePolicyDocument",
+				"resource_aws_s3_bucket":              "internal/service/s3 ResourceBucket",
+				"resource_aws_s3_directory_bucket":    "internal/service/s3 newDirectoryBucketResource",
+			},
+		},
+		{
+			"provider maps of version 4",
+			"testdata/providers/terraform-provider-aws-v4",
+			map[string]string{
+				"data_source_aws_sqs_queue": "internal/service/sqs DataSourceQueue",
+				"resource_aws_sqs_queue":    "internal/service/sqs ResourceQueue",
+			},
+		},
+		{
+			"resource files without registrations",
+			awsFixtureSource,
+			map[string]string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			registrations, err := collectAWSRegistrations(c.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, registration := range registrations {
+				dir, _ := filepath.Rel(c.source, registration.Dir)
+				got[registration.Key] = filepath.ToSlash(dir) + " " + registration.Factory
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAWSExtractor(t *testing.T) {
+	cases := []struct {
+		name     string
+		source   string
+		resource string
+		phase    Phase
+		want     []string
+	}{
+		{"accessor method of version 5", "terraform-provider-aws-v5", "resource_aws_s3_bucket", PhaseDelete, []string{"s3:DeleteBucket"}},
+		{"client kept in a variable", "terraform-provider-aws-v5", "resource_aws_s3_bucket", PhaseUpdate, []string{"s3:GetObjectVersion", "s3:HeadBucket", "s3:ListBucket", "s3:PutBucketTagging"}},
+		{"paginator and helper function", "terraform-provider-aws-v5", "resource_aws_s3_bucket", PhaseRead, []string{"s3:GetObjectVersion", "s3:HeadBucket", "s3:ListBucket"}},
+		{"plugin framework resource", "terraform-provider-aws-v5", "resource_aws_s3_directory_bucket", PhaseCreate, []string{"s3:CreateBucket"}},
+		{"plugin framework resource without update", "terraform-provider-aws-v5", "resource_aws_s3_directory_bucket", PhaseUpdate, []string{}},
+		{"aws-sdk-go connection and no HTTP client", "terraform-provider-aws-v5", "data_source_aws_ami", PhaseRead, []string{"ec2:DescribeImages"}},
+		{"resource without calls", "terraform-provider-aws-v5", "data_source_aws_iam_policy_document", PhaseRead, []string{}},
+		{"connection field of version 4", "terraform-provider-aws-v4", "resource_aws_sqs_queue", PhaseCreate, []string{"sqs:CreateQueue", "sqs:GetQueueAttributes"}},
+		{"data source of version 4", "terraform-provider-aws-v4", "data_source_aws_sqs_queue", PhaseRead, []string{"sqs:GetQueueAttributes", "sqs:GetQueueUrl"}},
+		{"resource file name", "terraform-provider-aws", "resource_aws_s3_bucket", PhaseRead, []string{"s3:GetEncryptionConfiguration", "s3:GetObject", "s3:GetObjectVersion"}},
+	}
+	mappings := make(map[string]*Mapping)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mapping := mappings[c.source]
+			if mapping == nil {
+				var err error
+				if mapping, err = NewAWSExtractor().Extract(filepath.Join("testdata/providers", c.source)); err != nil {
+					t.Fatal(err)
+				}
+				mappings[c.source] = mapping
+			}
+			resource := mapping.Resources[c.resource]
+			if resource == nil {
+				t.Fatalf("%s is not in the mapping", c.resource)
+			}
+			got := []string{}
+			for _, action := range resource.Get(c.phase) {
+				got = append(got, action.Action)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestAWSExtractorRecordsWhereActionsAreFound(t *testing.T) {
+	mapping, err := NewAWSExtractor().Extract("testdata/providers/terraform-provider-aws-v5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := mapping.Resources["resource_aws_s3_directory_bucket"].Delete
+	want := MappedAction{Action: "s3:DeleteBucket", File: "internal/service/s3/directory_bucket.go", Line: 35}
+	if len(got) != 1 || *got[0] != want {
+		t.Errorf("got %d actions, want %+v", len(got), want)
+	}
+}
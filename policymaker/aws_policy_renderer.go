@@ -0,0 +1,167 @@
+package policymaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+AWSRenderer renders the IAM policy of the AWS resources as aws_policy.json. Policies larger than
+the managed policy size limit are split by service into aws_policy_1.json, aws_policy_2.json, etc.
+The actions are validated against the action catalogue in aws_action_validation.json.
+*/
+type AWSRenderer struct {
+	CompressActions bool
+	ActionCatalogue *ActionCatalogue
+}
+
+// NewAWSRenderer is the Constructor for AWSRenderer
+func NewAWSRenderer(o *RenderOptions) *AWSRenderer {
+	return &AWSRenderer{
+		CompressActions: o.CompressActions,
+		ActionCatalogue: o.ActionCatalogue,
+	}
+}
+
+// OutputPatterns returns the patterns of the policies, split or not, and of the validation
+func (r *AWSRenderer) OutputPatterns() []string {
+	return []string{fmt.Sprintf("%s_policy*.json", awsProvider), fmt.Sprintf("%s_action_validation.json", awsProvider)}
+}
+
+// Render returns the policy documents and the validation of their actions
+func (r *AWSRenderer) Render(actions []*ResourceActions, values *PlanValues) ([]*Document, error) {
+	document := r.BuildPolicyDocument(actions, values)
+	policies := SplitPolicyDocument(document, ManagedPolicySizeLimit)
+	var documents []*Document
+	for i, policy := range policies {
+		content, err := policy.JSON()
+		if err != nil {
+			return nil, &PolicyError{Op: "marshal policy", Err: err}
+		}
+		fileName := fmt.Sprintf("%s_policy.json", awsProvider)
+		if len(policies) > 1 {
+			fileName = fmt.Sprintf("%s_policy_%d.json", awsProvider, i+1)
+		}
+		documents = append(documents, &Document{
+			FileName: fileName,
+			Content:  content,
+			Summary:  fmt.Sprintf("%d characters", PolicySize(policy)),
+		})
+	}
+	//Validate the actions against the catalogue and report the ones that could not be found
+	validation := r.ValidateActions(policies...)
+	validationFileName := fmt.Sprintf("%s_action_validation.json", awsProvider)
+	content, err := json.MarshalIndent(validation, "", "  ")
+	if err != nil {
+		return nil, &PolicyError{Op: "marshal action validation", Err: err}
+	}
+	documents = append(documents, &Document{
+		FileName: validationFileName,
+		Content:  content,
+		Summary:  fmt.Sprintf("%d unknown, %d unverified actions", len(validation.Unknown), len(validation.Unverified)),
+	})
+	if len(validation.Unknown) > 0 {
+		fmt.Printf("######### Warning: %d actions do not exist in action catalogue %s, see %s\n", len(validation.Unknown), validation.CatalogueVersion, validationFileName)
+	}
+	if len(validation.Unverified) > 0 {
+		fmt.Printf("######### Warning: action catalogue %s does not cover %s, so %d actions could not be validated, refresh it with update-catalogue\n", validation.CatalogueVersion, strings.Join(validation.UnverifiedServices(), ", "), len(validation.Unverified))
+	}
+	if r.CompressActions && len(validation.Unverified) > 0 {
+		fmt.Printf("######### Warning: the actions of %s are not compressed, as action catalogue %s does not cover them\n", strings.Join(validation.UnverifiedServices(), ", "), validation.CatalogueVersion)
+	}
+	if len(policies) > AttachedPoliciesQuota {
+		fmt.Printf("######### Warning: %d policies exceed the default quota of %d managed policies attached to a role\n", len(policies), AttachedPoliciesQuota)
+	}
+	return documents, nil
+}
+
+/*
+BuildPolicyDocument builds an IAM policy document granting the actions of the AWS resources.
+Actions are scoped to the ARNs of the planned resources wherever possible, and granted on "*" otherwise.
+When CompressActions is set the actions of each statement are collapsed into wildcards.
+*/
+func (r *AWSRenderer) BuildPolicyDocument(actions []*ResourceActions, values *PlanValues) *PolicyDocument {
+	fmt.Println("######### New Policy")
+	instancesMap := make(map[string][]*PlannedResource)
+	for _, instance := range values.Resources {
+		if instance.ProviderName() != awsProvider {
+			continue
+		}
+		instancesMap[instance.ToString()] = append(instancesMap[instance.ToString()], instance)
+	}
+	//collect the ARNs each permission should be granted on
+	permissionsSet := make(map[string]map[string]bool)
+	for _, resourceActions := range actions {
+		instances := instancesMap[resourceActions.Resource.ToString()]
+		for _, permission := range resourceActions.Actions {
+			if permissionsSet[permission] == nil {
+				permissionsSet[permission] = make(map[string]bool)
+			}
+			scoped := len(instances) > 0
+			for _, instance := range instances {
+				arns := scopeARNs(permission, instance, values.Region, values.AccountID, r.ActionCatalogue)
+				if len(arns) == 0 {
+					scoped = false
+					break
+				}
+				for _, arn := range arns {
+					permissionsSet[permission][arn] = true
+				}
+			}
+			if !scoped {
+				permissionsSet[permission]["*"] = true
+			}
+		}
+	}
+	//group permissions that are granted on the same ARNs into a single statement
+	statementsMap := make(map[string]*Statement)
+	for permission, arnsSet := range permissionsSet {
+		arnsList := []string{"*"}
+		if !arnsSet["*"] {
+			arnsList = make([]string, 0, len(arnsSet))
+			for arn := range arnsSet {
+				arnsList = append(arnsList, arn)
+			}
+			sort.Strings(arnsList)
+		}
+		key := strings.Join(arnsList, ",")
+		if statementsMap[key] == nil {
+			statementsMap[key] = &Statement{Effect: EffectAllow, Resource: arnsList}
+		}
+		statementsMap[key].Action = append(statementsMap[key].Action, permission)
+	}
+	statementsKeys := make([]string, 0, len(statementsMap))
+	for key := range statementsMap {
+		statementsKeys = append(statementsKeys, key)
+	}
+	sort.Strings(statementsKeys)
+	document := NewPolicyDocument()
+	scopedCount := 0
+	for _, key := range statementsKeys {
+		statement := statementsMap[key]
+		sort.Strings(statement.Action)
+		if r.CompressActions {
+			statement.Action = CompressActions(statement.Action, r.ActionCatalogue)
+		}
+		statement.Sid = "Unscoped"
+		if key != "*" {
+			scopedCount++
+			statement.Sid = fmt.Sprintf("Scoped%d", scopedCount)
+		}
+		document.Statement = append(document.Statement, statement)
+	}
+	return document
+}
+
+// ValidateActions checks every action in the policy documents against the action catalogue
+func (r *AWSRenderer) ValidateActions(documents ...*PolicyDocument) *ActionValidation {
+	var actions []string
+	for _, document := range documents {
+		for _, statement := range document.Statement {
+			actions = append(actions, statement.Action...)
+		}
+	}
+	return r.ActionCatalogue.Validate(actions)
+}
@@ -0,0 +1,329 @@
+package policymaker
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+These are the methods of a service package in service_package_gen.go that list its resources, e.g.
+{Factory: ResourceBucket, TypeName: "aws_s3_bucket"}, and whether they list data sources
+*/
+var awsServicePackageMethods = map[string]bool{
+	"SDKResources":         false,
+	"SDKDataSources":       true,
+	"FrameworkResources":   false,
+	"FrameworkDataSources": true,
+}
+
+/*
+collectAWSRegistrations finds the resources and data sources terraform-provider-aws registers, along
+with the functions that return them. Since version 5 every service package registers its own in
+service_package_gen.go, and before that the provider lists all of them in the ResourcesMap and
+DataSourcesMap of provider.go, e.g. "aws_s3_bucket": s3.ResourceBucket(). The resource files of
+those versions are named after the resource without the provider, e.g. internal/service/s3/bucket.go,
+so they cannot be found by name.
+*/
+func collectAWSRegistrations(sourceDir string) ([]*resourceRegistration, error) {
+	modulePath := goModulePath(sourceDir)
+	registrationsMap := make(map[string]*resourceRegistration)
+	fset := token.NewFileSet()
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "vendor" || info.Name() == "testdata" || strings.HasPrefix(info.Name(), ".") && path != sourceDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != "service_package_gen.go" && info.Name() != "provider.go" {
+			return nil
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		var registrations []*resourceRegistration
+		if info.Name() == "service_package_gen.go" {
+			registrations = servicePackageRegistrations(file, filepath.Dir(path))
+		} else {
+			registrations = providerMapRegistrations(file, filepath.Dir(path), func(importPath string) string {
+				return importDir(sourceDir, modulePath, importPath)
+			})
+		}
+		for _, registration := range registrations {
+			registrationsMap[registration.Key] = registration
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	registrations := make([]*resourceRegistration, 0, len(registrationsMap))
+	for _, registration := range registrationsMap {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].Key < registrations[j].Key
+	})
+	return registrations, nil
+}
+
+/*
+helper function that returns the registrations of a service_package_gen.go file. The plugin framework
+resources of some versions leave out the TypeName, which is then read from their Metadata method.
+*/
+func servicePackageRegistrations(file *ast.File, dir string) []*resourceRegistration {
+	var registrations []*resourceRegistration
+	var typeNames map[string]string
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || fd.Body == nil {
+			continue
+		}
+		isDataSource, ok := awsServicePackageMethods[fd.Name.Name]
+		if !ok {
+			continue
+		}
+		mode := "resource"
+		if isDataSource {
+			mode = "data_source"
+		}
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			factory, typeName := registrationFields(lit)
+			if factory == "" {
+				return true
+			}
+			if typeName == "" {
+				if typeNames == nil {
+					typeNames = frameworkTypeNames(dir)
+				}
+				typeName = typeNames[factory]
+			}
+			if typeName == "" {
+				fmt.Printf("######### Warning: the type name of %s in %s is not known, it is left out of the mapping\n", factory, dir)
+				return false
+			}
+			registrations = append(registrations, &resourceRegistration{
+				Key:     resourceKey(mode, typeName, awsProvider),
+				Dir:     dir,
+				Factory: factory,
+			})
+			return false
+		})
+	}
+	return registrations
+}
+
+// helper function that reads the Factory and TypeName fields of a registration literal
+func registrationFields(lit *ast.CompositeLit) (string, string) {
+	var factory, typeName string
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		switch key.Name {
+		case "Factory":
+			factory = funcName(kv.Value)
+		case "TypeName":
+			typeName = stringLiteral(kv.Value)
+		}
+	}
+	return factory, typeName
+}
+
+/*
+helper function that returns the registrations of the ResourcesMap and DataSourcesMap in a provider.go
+file. The functions may be declared in the same package or, qualified by an import, in another one,
+whose directory importDir returns.
+*/
+func providerMapRegistrations(file *ast.File, dir string, importDir func(importPath string) string) []*resourceRegistration {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+	var registrations []*resourceRegistration
+	ast.Inspect(file, func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok || key.Name != "ResourcesMap" && key.Name != "DataSourcesMap" {
+			return true
+		}
+		lit, ok := kv.Value.(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		mode := "resource"
+		if key.Name == "DataSourcesMap" {
+			mode = "data_source"
+		}
+		for _, elt := range lit.Elts {
+			entry, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			call, ok := entry.Value.(*ast.CallExpr)
+			typeName := stringLiteral(entry.Key)
+			if !ok || typeName == "" {
+				continue
+			}
+			factoryDir := dir
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				pkg, ok := sel.X.(*ast.Ident)
+				if !ok || imports[pkg.Name] == "" {
+					continue
+				}
+				if factoryDir = importDir(imports[pkg.Name]); factoryDir == "" {
+					continue
+				}
+			}
+			registrations = append(registrations, &resourceRegistration{
+				Key:     resourceKey(mode, typeName, awsProvider),
+				Dir:     factoryDir,
+				Factory: funcName(call.Fun),
+			})
+		}
+		return false
+	})
+	return registrations
+}
+
+/*
+frameworkTypeNames returns the type name each plugin framework resource of the package in dir sets in
+its Metadata method, e.g. response.TypeName = "aws_s3_directory_bucket", keyed by the function that
+instantiates the resource
+*/
+func frameworkTypeNames(dir string) map[string]string {
+	typeNames := make(map[string]string)
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return typeNames
+	}
+	factories := make(map[string]string)
+	metadata := make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				if fd.Recv == nil {
+					factories[fd.Name.Name] = instantiatedType(fd)
+				} else if fd.Name.Name == "Metadata" && len(fd.Recv.List) == 1 {
+					metadata[receiverTypeName(fd.Recv.List[0].Type)] = assignedTypeName(fd)
+				}
+			}
+		}
+	}
+	for factory, resourceType := range factories {
+		if typeName := metadata[resourceType]; typeName != "" {
+			typeNames[factory] = typeName
+		}
+	}
+	return typeNames
+}
+
+// helper function that returns the string literal a function assigns to a TypeName field
+func assignedTypeName(fd *ast.FuncDecl) string {
+	var typeName string
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return true
+		}
+		if sel, ok := assign.Lhs[0].(*ast.SelectorExpr); ok && sel.Sel.Name == "TypeName" {
+			typeName = stringLiteral(assign.Rhs[0])
+		}
+		return typeName == ""
+	})
+	return typeName
+}
+
+// helper function that returns the name of the first package type a function instantiates, e.g. bucketResource for &bucketResource{}
+func instantiatedType(fd *ast.FuncDecl) string {
+	var typeName string
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			if ident, ok := lit.Type.(*ast.Ident); ok {
+				typeName = ident.Name
+			}
+		}
+		return typeName == ""
+	})
+	return typeName
+}
+
+// helper function that returns the type name of a method receiver, e.g. bucketResource for *bucketResource
+func receiverTypeName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// helper function that returns the value of a string literal, or an empty string if expr is not one
+func stringLiteral(expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return ""
+	}
+	value, _ := strconv.Unquote(lit.Value)
+	return value
+}
+
+// helper function that reads the module path from the go.mod file in dir, or returns an empty string
+func goModulePath(dir string) string {
+	file, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// helper function that returns the directory of a package of the module in sourceDir, or an empty string if it is not one
+func importDir(sourceDir string, modulePath string, importPath string) string {
+	if modulePath == "" || !strings.HasPrefix(importPath, modulePath+"/") {
+		return ""
+	}
+	return filepath.Join(sourceDir, filepath.FromSlash(strings.TrimPrefix(importPath, modulePath+"/")))
+}
@@ -0,0 +1,259 @@
+package policymaker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// awsARNBuilder builds the ARNs of a resource instance from its planned values
+type awsARNBuilder func(values gjson.Result, region string, accountID string) []string
+
+/*
+This maps terraform resource types to the ARNs their actions can be scoped to. Resource
+types that are not listed here, or whose name is not known until apply, are granted "*".
+*/
+var awsResourceARNBuilders = map[string]awsARNBuilder{
+	"aws_s3_bucket": func(values gjson.Result, region string, accountID string) []string {
+		bucket := values.Get("bucket").String()
+		if bucket == "" {
+			return nil
+		}
+		partition := awsPartition(region)
+		return []string{"arn:" + partition + ":s3:::" + bucket, "arn:" + partition + ":s3:::" + bucket + "/*"}
+	},
+	"aws_ecr_repository": namedARNBuilder("ecr", "name", "repository/%s"),
+	"aws_lambda_function": func(values gjson.Result, region string, accountID string) []string {
+		name := values.Get("function_name").String()
+		if name == "" {
+			return nil
+		}
+		return []string{
+			awsARN("lambda", region, accountID, "function:"+name),
+			awsARN("lambda", region, accountID, "function:"+name+":*"),
+		}
+	},
+	"aws_iam_role": func(values gjson.Result, region string, accountID string) []string {
+		name := values.Get("name").String()
+		if name == "" {
+			return nil
+		}
+		path := values.Get("path").String()
+		if path == "" {
+			path = "/"
+		}
+		return []string{awsARN("iam", region, accountID, "role"+path+name)}
+	},
+	"aws_sqs_queue":             namedARNBuilder("sqs", "name", "%s"),
+	"aws_sns_topic":             namedARNBuilder("sns", "name", "%s"),
+	"aws_dynamodb_table":        namedARNBuilder("dynamodb", "name", "table/%s", "table/%s/*"),
+	"aws_cloudwatch_log_group":  namedARNBuilder("logs", "name", "log-group:%s", "log-group:%s:*"),
+	"aws_kinesis_stream":        namedARNBuilder("kinesis", "name", "stream/%s"),
+	"aws_secretsmanager_secret": namedARNBuilder("secretsmanager", "name", "secret:%s-*"),
+	"aws_ssm_parameter": func(values gjson.Result, region string, accountID string) []string {
+		name := strings.TrimPrefix(values.Get("name").String(), "/")
+		if name == "" {
+			return nil
+		}
+		return []string{awsARN("ssm", region, accountID, "parameter/"+name)}
+	},
+	"aws_cloudwatch_event_rule":   namedARNBuilder("events", "name", "rule/%s"),
+	"aws_sfn_state_machine":       namedARNBuilder("states", "name", "stateMachine:%s"),
+	"aws_elasticache_cluster":     namedARNBuilder("elasticache", "cluster_id", "cluster:%s"),
+	"aws_db_instance":             namedARNBuilder("rds", "identifier", "db:%s"),
+	"aws_codecommit_repository":   namedARNBuilder("codecommit", "repository_name", "%s"),
+	"aws_codebuild_project":       namedARNBuilder("codebuild", "name", "project/%s"),
+	"aws_glue_catalog_database":   namedARNBuilder("glue", "name", "database/%s"),
+	"aws_athena_workgroup":        namedARNBuilder("athena", "name", "workgroup/%s"),
+	"aws_ecs_cluster":             namedARNBuilder("ecs", "name", "cluster/%s"),
+	"aws_kms_alias":               namedARNBuilder("kms", "name", "%s"),
+	"aws_cloudformation_stack":    namedARNBuilder("cloudformation", "name", "stack/%s/*"),
+	"aws_cloudwatch_metric_alarm": namedARNBuilder("cloudwatch", "alarm_name", "alarm:%s"),
+}
+
+/*
+Some actions do not support resource level permissions at all, so they must always be
+granted on "*" regardless of which resources are being deployed
+*/
+var awsUnscopableActions = map[string]bool{
+	"s3:ListAllMyBuckets":             true,
+	"ecr:GetAuthorizationToken":       true,
+	"lambda:ListFunctions":            true,
+	"lambda:CreateEventSourceMapping": true,
+	"lambda:ListEventSourceMappings":  true,
+	"iam:ListRoles":                   true,
+	"sqs:ListQueues":                  true,
+	"sns:ListTopics":                  true,
+	"dynamodb:ListTables":             true,
+	"logs:DescribeLogGroups":          true,
+	"kinesis:ListStreams":             true,
+	"secretsmanager:ListSecrets":      true,
+	"ssm:DescribeParameters":          true,
+	"events:ListRules":                true,
+	"states:ListStateMachines":        true,
+	"codebuild:ListProjects":          true,
+	"ecs:ListClusters":                true,
+	"kms:ListAliases":                 true,
+	"sts:GetCallerIdentity":           true,
+}
+
+/*
+These actions need other resources besides the one they create or change, e.g. kms:CreateAlias needs
+the key the alias points to and rds:CreateDBInstance needs its subnet, parameter and option groups, so
+granting them on the ARN of that resource alone would be denied. Services the action catalogue covers
+are checked against the resource types of each action instead.
+*/
+var awsMultiResourceActions = map[string]bool{
+	"kms:CreateAlias":                                true,
+	"kms:UpdateAlias":                                true,
+	"kms:DeleteAlias":                                true,
+	"glue:CreateDatabase":                            true,
+	"glue:GetDatabase":                               true,
+	"glue:UpdateDatabase":                            true,
+	"glue:DeleteDatabase":                            true,
+	"rds:CreateDBInstance":                           true,
+	"rds:CreateDBInstanceReadReplica":                true,
+	"rds:ModifyDBInstance":                           true,
+	"rds:RestoreDBInstanceFromDBSnapshot":            true,
+	"rds:RestoreDBInstanceFromS3":                    true,
+	"rds:RestoreDBInstanceToPointInTime":             true,
+	"elasticache:CreateCacheCluster":                 true,
+	"elasticache:ModifyCacheCluster":                 true,
+	"elasticache:CreateReplicationGroup":             true,
+	"elasticache:ModifyReplicationGroup":             true,
+	"elasticache:CreateCacheSubnetGroup":             true,
+	"elasticache:ModifyCacheParameterGroup":          true,
+	"elasticache:AuthorizeCacheSecurityGroupIngress": true,
+}
+
+// helper function for resource types whose ARN only depends on a single name attribute
+func namedARNBuilder(service string, attribute string, formats ...string) awsARNBuilder {
+	return func(values gjson.Result, region string, accountID string) []string {
+		name := values.Get(attribute).String()
+		if name == "" {
+			return nil
+		}
+		arns := make([]string, len(formats))
+		for i, format := range formats {
+			arns[i] = awsARN(service, region, accountID, fmt.Sprintf(format, name))
+		}
+		return arns
+	}
+}
+
+/*
+These are the region prefixes of the partitions other than aws, e.g. GovCloud regions are named
+like us-gov-west-1
+*/
+var awsPartitionRegionPrefixes = []struct {
+	prefix    string
+	partition string
+}{
+	{"cn-", "aws-cn"},
+	{"us-gov-", "aws-us-gov"},
+	{"us-isob-", "aws-iso-b"},
+	{"us-iso-", "aws-iso"},
+}
+
+// helper function that returns the partition of a region, or "*" if the region is not known
+func awsPartition(region string) string {
+	if region == "" || strings.ContainsAny(region, "*?") {
+		return "*"
+	}
+	for _, p := range awsPartitionRegionPrefixes {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return "aws"
+}
+
+/*
+helper function that builds an ARN in the partition of the region, using wildcards for the parts
+that are not known. IAM is a global service, so its ARNs have no region.
+*/
+func awsARN(service string, region string, accountID string, resource string) string {
+	partition := awsPartition(region)
+	if service == "iam" {
+		region = ""
+	} else if region == "" {
+		region = "*"
+	}
+	if accountID == "" {
+		accountID = "*"
+	}
+	return fmt.Sprintf("arn:%s:%s:%s:%s:%s", partition, service, region, accountID, resource)
+}
+
+/*
+scopeARNs returns the ARNs that an action used by a planned resource can be restricted to.
+An empty result means the action must be granted on "*".
+*/
+func scopeARNs(action string, resource *PlannedResource, region string, accountID string, catalogue *ActionCatalogue) []string {
+	if awsUnscopableActions[action] || awsMultiResourceActions[action] || strings.HasSuffix(action, ":*") {
+		return nil
+	}
+	builder := awsResourceARNBuilders[resource.Type]
+	if builder == nil {
+		return nil
+	}
+	arns := builder(resource.Values, region, accountID)
+	for _, arn := range arns {
+		// only actions of the same service as the resource can be scoped to it, the service is the third part of the ARN
+		if parts := strings.SplitN(arn, ":", 4); len(parts) < 4 || parts[2] != strings.Split(action, ":")[0] {
+			return nil
+		}
+	}
+	if !catalogueAllowsScoping(catalogue, action, arns) {
+		return nil
+	}
+	return arns
+}
+
+/*
+helper function that checks an action against the resource types the catalogue lists for it: every
+resource type needs one of the ARNs, so that no other resource the action needs is left out, and an
+action without resource types only supports "*". Actions the catalogue does not know are allowed.
+*/
+func catalogueAllowsScoping(catalogue *ActionCatalogue, action string, arns []string) bool {
+	parts := strings.SplitN(action, ":", 2)
+	if catalogue == nil || len(parts) < 2 || catalogue.Services[parts[0]] == nil {
+		return true
+	}
+	service := catalogue.Services[parts[0]]
+	reference := service.Actions[parts[1]]
+	if reference == nil {
+		return true
+	}
+	if len(reference.ResourceTypes) == 0 {
+		return false
+	}
+	for _, resourceType := range reference.ResourceTypes {
+		format := service.ResourceTypes[resourceType]
+		if format == "" {
+			continue
+		}
+		matched := false
+		for _, arn := range arns {
+			if arnFormatPattern(format).MatchString(arn) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// arnFormatVariable matches a variable like ${Region} in an ARN format, once it is quoted
+var arnFormatVariable = regexp.MustCompile(`\\\$\\\{[^}]*\\\}`)
+
+// helper function that turns an ARN format like arn:${Partition}:sqs:${Region}:${Account}:${QueueName} into a pattern
+func arnFormatPattern(format string) *regexp.Regexp {
+	pattern := arnFormatVariable.ReplaceAllString(regexp.QuoteMeta(format), ".*")
+	return regexp.MustCompile("^" + pattern + "$")
+}
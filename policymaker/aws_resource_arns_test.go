@@ -0,0 +1,80 @@
+package policymaker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestScopeARNsPartition(t *testing.T) {
+	cases := []struct {
+		region string
+		want   []string
+	}{
+		{"us-west-2", []string{"arn:aws:sqs:us-west-2:123456789012:queue"}},
+		{"us-gov-west-1", []string{"arn:aws-us-gov:sqs:us-gov-west-1:123456789012:queue"}},
+		{"cn-north-1", []string{"arn:aws-cn:sqs:cn-north-1:123456789012:queue"}},
+		{"us-isob-east-1", []string{"arn:aws-iso-b:sqs:us-isob-east-1:123456789012:queue"}},
+		{"", []string{"arn:*:sqs:*:123456789012:queue"}},
+	}
+	for _, c := range cases {
+		resource := &PlannedResource{Resource: &Resource{Type: "aws_sqs_queue"}, Values: gjson.Parse(`{"name": "queue"}`)}
+		got := scopeARNs("sqs:SendMessage", resource, c.region, "123456789012", nil)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("region %q: got %v, want %v", c.region, got, c.want)
+		}
+	}
+}
+
+func TestScopeARNsGlobalServices(t *testing.T) {
+	role := &PlannedResource{Resource: &Resource{Type: "aws_iam_role"}, Values: gjson.Parse(`{"name": "deployer"}`)}
+	if got, want := scopeARNs("iam:GetRole", role, "cn-north-1", "123456789012", nil), []string{"arn:aws-cn:iam::123456789012:role/deployer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	bucket := &PlannedResource{Resource: &Resource{Type: "aws_s3_bucket"}, Values: gjson.Parse(`{"bucket": "logs"}`)}
+	if got, want := scopeARNs("s3:GetObject", bucket, "us-gov-east-1", "", nil), []string{"arn:aws-us-gov:s3:::logs", "arn:aws-us-gov:s3:::logs/*"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// actions of another service cannot be scoped to the resource
+	if got := scopeARNs("sqs:SendMessage", bucket, "us-gov-east-1", "", nil); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+}
+
+func TestScopeARNsOfActionsNeedingOtherResources(t *testing.T) {
+	catalogue, err := parseActionCatalogue([]byte(`{"version": "test", "services": {"ecr": {
+		"resource_types": {"repository": "arn:${Partition}:ecr:${Region}:${Account}:repository/${RepositoryName}", "key": "arn:${Partition}:kms:${Region}:${Account}:key/${KeyId}"},
+		"actions": {
+			"CreateRepository": {"resource_types": ["repository"]},
+			"PutReplicationConfiguration": {"resource_types": ["repository", "key"]},
+			"GetAuthorizationToken": {}
+		}
+	}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := &PlannedResource{Resource: &Resource{Type: "aws_ecr_repository"}, Values: gjson.Parse(`{"name": "app"}`)}
+	cases := []struct {
+		action   string
+		resource *PlannedResource
+		want     []string
+	}{
+		{"ecr:CreateRepository", repository, []string{"arn:aws:ecr:us-east-1:123456789012:repository/app"}},
+		// the catalogue lists a resource type the repository ARN does not match
+		{"ecr:PutReplicationConfiguration", repository, nil},
+		// the catalogue lists no resource types, so the action only supports "*"
+		{"ecr:GetAuthorizationToken", repository, nil},
+		// kms:CreateAlias needs the key as well as the alias
+		{"kms:CreateAlias", &PlannedResource{Resource: &Resource{Type: "aws_kms_alias"}, Values: gjson.Parse(`{"name": "alias/app"}`)}, nil},
+		{"rds:CreateDBInstance", &PlannedResource{Resource: &Resource{Type: "aws_db_instance"}, Values: gjson.Parse(`{"identifier": "app"}`)}, nil},
+		{"rds:DeleteDBInstance", &PlannedResource{Resource: &Resource{Type: "aws_db_instance"}, Values: gjson.Parse(`{"identifier": "app"}`)}, []string{"arn:aws:rds:us-east-1:123456789012:db:app"}},
+	}
+	for _, c := range cases {
+		t.Run(c.action, func(t *testing.T) {
+			if got := scopeARNs(c.action, c.resource, "us-east-1", "123456789012", catalogue); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
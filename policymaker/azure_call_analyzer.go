@@ -0,0 +1,474 @@
+package policymaker
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// azureClientStructName is the name of the structs terraform-provider-azurerm keeps its SDK clients in
+const azureClientStructName = "Client"
+
+/*
+azureSDKPackagePrefixes are the import paths of the Azure SDKs, each followed by the directory of
+a service, e.g. github.com/Azure/azure-sdk-for-go/services/web/mgmt/2020-06-01/web
+*/
+var azureSDKPackagePrefixes = []string{
+	"github.com/Azure/azure-sdk-for-go/services/preview/",
+	"github.com/Azure/azure-sdk-for-go/services/",
+	"github.com/hashicorp/go-azure-sdk/resource-manager/",
+}
+
+/*
+azureNamespaces maps the service directories of the Azure SDKs to their resource provider
+namespace, where it is not simply Microsoft. followed by the capitalized directory
+*/
+var azureNamespaces = map[string]string{
+	"apimanagement":       "Microsoft.ApiManagement",
+	"appconfiguration":    "Microsoft.AppConfiguration",
+	"appinsights":         "Microsoft.Insights",
+	"authorization":       "Microsoft.Authorization",
+	"containerinstance":   "Microsoft.ContainerInstance",
+	"containerregistry":   "Microsoft.ContainerRegistry",
+	"containerservice":    "Microsoft.ContainerService",
+	"cosmos-db":           "Microsoft.DocumentDB",
+	"datafactory":         "Microsoft.DataFactory",
+	"dns":                 "Microsoft.Network",
+	"documentdb":          "Microsoft.DocumentDB",
+	"eventhub":            "Microsoft.EventHub",
+	"keyvault":            "Microsoft.KeyVault",
+	"monitor":             "Microsoft.Insights",
+	"msi":                 "Microsoft.ManagedIdentity",
+	"mysql":               "Microsoft.DBforMySQL",
+	"operationalinsights": "Microsoft.OperationalInsights",
+	"postgresql":          "Microsoft.DBforPostgreSQL",
+	"privatedns":          "Microsoft.Network",
+	"recoveryservices":    "Microsoft.RecoveryServices",
+	"redis":               "Microsoft.Cache",
+	"servicebus":          "Microsoft.ServiceBus",
+}
+
+/*
+azureResourceTypes maps the SDK clients whose name does not match the resource type they manage,
+e.g. web.AppsClient manages Microsoft.Web/sites
+*/
+var azureResourceTypes = map[string]string{
+	"network.InterfacesClient":            "Microsoft.Network/networkInterfaces",
+	"network.SecurityGroupsClient":        "Microsoft.Network/networkSecurityGroups",
+	"network.SecurityRulesClient":         "Microsoft.Network/networkSecurityGroups/securityRules",
+	"network.SubnetsClient":               "Microsoft.Network/virtualNetworks/subnets",
+	"resources.GroupsClient":              "Microsoft.Resources/subscriptions/resourceGroups",
+	"resourcegroups.ResourceGroupsClient": "Microsoft.Resources/subscriptions/resourceGroups",
+	"sql.DatabasesClient":                 "Microsoft.Sql/servers/databases",
+	"storage.AccountsClient":              "Microsoft.Storage/storageAccounts",
+	"storage.BlobContainersClient":        "Microsoft.Storage/storageAccounts/blobServices/containers",
+	"web.AppServicePlansClient":           "Microsoft.Web/serverfarms",
+	"web.AppsClient":                      "Microsoft.Web/sites",
+	"webapps.WebAppsClient":               "Microsoft.Web/sites",
+}
+
+/*
+azureIdiosyncracyOperationMap maps the SDK methods that do not follow the naming of the operation
+they need, e.g. the settings of a site are a config sub-resource
+*/
+var azureIdiosyncracyOperationMap = map[string]string{
+	"resources.GroupsClient.CheckExistence":      "Microsoft.Resources/subscriptions/resourceGroups/read",
+	"web.AppsClient.GetConfiguration":            "Microsoft.Web/sites/config/read",
+	"web.AppsClient.ListApplicationSettings":     "Microsoft.Web/sites/config/list/action",
+	"web.AppsClient.ListPublishingCredentials":   "Microsoft.Web/sites/config/list/action",
+	"web.AppsClient.UpdateApplicationSettings":   "Microsoft.Web/sites/config/write",
+	"web.AppsClient.UpdateConfiguration":         "Microsoft.Web/sites/config/write",
+	"web.AppsClient.UpdateConnectionStrings":     "Microsoft.Web/sites/config/write",
+	"web.AppsClient.ListConnectionStrings":       "Microsoft.Web/sites/config/list/action",
+	"web.AppsClient.GetSourceControl":            "Microsoft.Web/sites/sourcecontrols/read",
+	"web.AppsClient.CreateOrUpdateSourceControl": "Microsoft.Web/sites/sourcecontrols/write",
+	"web.AppsClient.DeleteSourceControl":         "Microsoft.Web/sites/sourcecontrols/delete",
+}
+
+// azureOperationVerbs maps the first word of an SDK method to the verb of the operation it needs
+var azureOperationVerbs = map[string]string{
+	"Get":    "read",
+	"List":   "read",
+	"Check":  "read",
+	"Exists": "read",
+	"Create": "write",
+	"Update": "write",
+	"Put":    "write",
+	"Set":    "write",
+	"Patch":  "write",
+	"Add":    "write",
+	"Delete": "delete",
+	"Remove": "delete",
+}
+
+// azureListActionSuffixes are the endings of List methods that return secrets, which are actions rather than reads
+var azureListActionSuffixes = []string{"Keys", "Secrets", "Credentials", "SAS", "ConnectionStrings"}
+
+// azureClient is a client type of an Azure SDK, e.g. web.AppsClient
+type azureClient struct {
+	// Service is the service directory of the SDK package, e.g. web
+	Service string
+	// Package is the name of the SDK package, e.g. web
+	Package string
+	// Type is the name of the client type, e.g. AppsClient
+	Type string
+}
+
+// helper function that returns the client a type in an SDK package is, if it is one
+func newAzureClient(importPath string, typeName string) (azureClient, bool) {
+	if !strings.HasSuffix(typeName, "Client") || typeName == "Client" {
+		return azureClient{}, false
+	}
+	for _, prefix := range azureSDKPackagePrefixes {
+		if strings.HasPrefix(importPath, prefix) {
+			service := strings.Split(strings.TrimPrefix(importPath, prefix), "/")[0]
+			return azureClient{Service: service, Package: filepath.Base(importPath), Type: typeName}, true
+		}
+	}
+	return azureClient{}, false
+}
+
+func (c azureClient) String() string {
+	return c.Package + "." + c.Type
+}
+
+// ResourceType returns the resource type the client manages, e.g. Microsoft.Compute/virtualMachines
+func (c azureClient) ResourceType() string {
+	if resourceType, ok := azureResourceTypes[c.String()]; ok {
+		return resourceType
+	}
+	namespace, ok := azureNamespaces[c.Service]
+	if !ok {
+		namespace = "Microsoft." + strings.ToUpper(c.Service[:1]) + c.Service[1:]
+	}
+	return namespace + "/" + lowerFirst(strings.TrimSuffix(c.Type, "Client"))
+}
+
+/*
+Operation returns the resource provider operation a method of the client needs, e.g.
+Microsoft.Web/sites/write for CreateOrUpdate. Methods that are not a read, write or delete
+are actions, e.g. Microsoft.Web/sites/restart/action for Restart.
+*/
+func (c azureClient) Operation(method string) string {
+	if operation, ok := azureIdiosyncracyOperationMap[c.String()+"."+method]; ok {
+		return operation
+	}
+	// the pagination and long running operation variants need the same operation
+	method = strings.TrimSuffix(strings.TrimSuffix(method, "ThenPoll"), "Complete")
+	word := method
+	if i := strings.IndexFunc(method[1:], unicode.IsUpper); i >= 0 {
+		word = method[:i+1]
+	}
+	verb, ok := azureOperationVerbs[word]
+	if word == "List" {
+		for _, suffix := range azureListActionSuffixes {
+			if strings.HasSuffix(method, suffix) {
+				ok = false
+			}
+		}
+	}
+	if !ok {
+		return c.ResourceType() + "/" + lowerFirst(method) + "/action"
+	}
+	return c.ResourceType() + "/" + verb
+}
+
+/*
+azureClientIndex records which SDK client each field of the Client structs of terraform-provider-azurerm
+holds, e.g. AppServicesClient *web.AppsClient in internal/services/web/client, together with the field
+that gives access to those structs, e.g. Web in meta.(*clients.Client).Web.AppServicesClient
+*/
+type azureClientIndex struct {
+	// services maps a service field and client field, e.g. Web.AppServicesClient, to its client
+	services map[string]azureClient
+	// fields maps a client field to its client, unless different services hold different clients in it
+	fields map[string]azureClient
+}
+
+// newAzureClientIndex collects the Client structs of all the packages below the provider source root
+func newAzureClientIndex(root string) (*azureClientIndex, error) {
+	index := &azureClientIndex{
+		services: make(map[string]azureClient),
+		fields:   make(map[string]azureClient),
+	}
+	// the clients held by the Client struct of each directory, and the service fields that point at those directories
+	clientsByDir := make(map[string]map[string]azureClient)
+	serviceImports := make(map[string]string)
+	ambiguous := make(map[string]bool)
+	fset := token.NewFileSet()
+	err := filepath.Walk(root, func(path string, file os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if file.IsDir() && file.Name() == "vendor" {
+			return filepath.SkipDir
+		}
+		if file.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// skip the files that cannot declare a Client struct without parsing them
+		if !bytes.Contains(src, []byte(azureClientStructName+" struct")) {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, src, 0)
+		if err != nil {
+			return err
+		}
+		imports := importPaths(f)
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok || ts.Name.Name != azureClientStructName {
+					continue
+				}
+				for _, field := range st.Fields.List {
+					importPath, typeName := qualifiedType(field.Type, imports)
+					if importPath == "" {
+						continue
+					}
+					for _, name := range field.Names {
+						if typeName == azureClientStructName {
+							serviceImports[name.Name] = importPath
+							continue
+						}
+						client, ok := newAzureClient(importPath, typeName)
+						if !ok {
+							continue
+						}
+						dir := filepath.Dir(path)
+						if clientsByDir[dir] == nil {
+							clientsByDir[dir] = make(map[string]azureClient)
+						}
+						clientsByDir[dir][name.Name] = client
+						if existing, ok := index.fields[name.Name]; ok && existing != client {
+							ambiguous[name.Name] = true
+						}
+						index.fields[name.Name] = client
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name := range ambiguous {
+		delete(index.fields, name)
+	}
+	for service, importPath := range serviceImports {
+		for dir, clients := range clientsByDir {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil || !strings.HasSuffix(importPath, "/"+filepath.ToSlash(rel)) {
+				continue
+			}
+			for name, client := range clients {
+				index.services[service+"."+name] = client
+			}
+		}
+	}
+	return index, nil
+}
+
+/*
+azureCallAnalyzer finds the resource provider operations (e.g. Microsoft.Web/sites/write) needed by
+the method calls made on the clients of the Azure SDK, e.g.
+client := meta.(*clients.Client).Web.AppServicesClient followed by client.CreateOrUpdate(...)
+*/
+type azureCallAnalyzer struct {
+	*callGraph
+	index *azureClientIndex
+	// params maps a function parameter declared as an SDK client, e.g. *web.AppsClient, to its client
+	params map[types.Object]azureClient
+}
+
+// newAzureCallAnalyzer type checks the package in a directory and collects its client calls
+func newAzureCallAnalyzer(dir string, index *azureClientIndex) (*azureCallAnalyzer, error) {
+	g, err := newCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	a := &azureCallAnalyzer{
+		callGraph: g,
+		index:     index,
+		params:    make(map[types.Object]azureClient),
+	}
+	g.findOperations = a.findOperations
+	g.operationPhases = azureOperationPhases
+	a.resolveTypedParams()
+	a.collectCalls()
+	return a, nil
+}
+
+// resolveTypedParams finds the parameters declared as an SDK client, e.g. func restart(client *web.AppsClient)
+func (a *azureCallAnalyzer) resolveTypedParams() {
+	for _, file := range a.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var ft *ast.FuncType
+			switch f := n.(type) {
+			case *ast.FuncDecl:
+				ft = f.Type
+			case *ast.FuncLit:
+				ft = f.Type
+			default:
+				return true
+			}
+			for _, field := range ft.Params.List {
+				client, ok := a.clientType(field.Type)
+				if !ok {
+					continue
+				}
+				for _, name := range field.Names {
+					if obj := a.objectOf(name); obj != nil {
+						a.params[obj] = client
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// helper function that returns the client of a type expression like *web.AppsClient
+func (a *azureCallAnalyzer) clientType(expr ast.Expr) (azureClient, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return azureClient{}, false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return azureClient{}, false
+	}
+	pkgName, ok := a.info.Uses[ident].(*types.PkgName)
+	if !ok {
+		return azureClient{}, false
+	}
+	return newAzureClient(pkgName.Imported().Path(), sel.Sel.Name)
+}
+
+/*
+findOperations records the operations of the client method calls within a node. The client
+a variable holds is tracked in source order, like the URLs of gcpCallAnalyzer.
+*/
+func (a *azureCallAnalyzer) findOperations(node ast.Node, operations map[string]token.Pos) {
+	clients := make(map[string]azureClient)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.AssignStmt:
+			if len(e.Lhs) != len(e.Rhs) {
+				return true
+			}
+			for i, lhs := range e.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if client, ok := a.clientOf(e.Rhs[i], clients); ok {
+					clients[ident.Name] = client
+				}
+			}
+		case *ast.CallExpr:
+			sel, ok := e.Fun.(*ast.SelectorExpr)
+			if !ok || !ast.IsExported(sel.Sel.Name) {
+				return true
+			}
+			if client, ok := a.clientOf(sel.X, clients); ok {
+				addOperation(operations, client.Operation(sel.Sel.Name), e.Pos())
+			}
+		}
+		return true
+	})
+}
+
+// helper function that returns the client an expression evaluates to, if any
+func (a *azureCallAnalyzer) clientOf(expr ast.Expr, clients map[string]azureClient) (azureClient, bool) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return a.clientOf(e.X, clients)
+	case *ast.Ident:
+		if client, ok := clients[e.Name]; ok {
+			return client, true
+		}
+		client, ok := a.params[a.objectOf(e)]
+		return client, ok
+	case *ast.SelectorExpr:
+		// e.g. meta.(*clients.Client).Web.AppServicesClient
+		if service, ok := e.X.(*ast.SelectorExpr); ok {
+			if client, ok := a.index.services[service.Sel.Name+"."+e.Sel.Name]; ok {
+				return client, true
+			}
+		}
+		client, ok := a.index.fields[e.Sel.Name]
+		return client, ok
+	}
+	return azureClient{}, false
+}
+
+/*
+azureOperationPhases classifies an operation like Microsoft.Web/sites/write by its verb. Writes both
+create and update a resource, and actions change an existing one.
+*/
+func azureOperationPhases(operation string) []Phase {
+	switch operation[strings.LastIndex(operation, "/")+1:] {
+	case "read":
+		return []Phase{PhaseRead}
+	case "write":
+		return []Phase{PhaseCreate, PhaseUpdate}
+	case "delete":
+		return []Phase{PhaseDelete}
+	}
+	return []Phase{PhaseUpdate}
+}
+
+// helper function that maps the names a file imports its packages by to their import paths
+func importPaths(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// helper function that returns the import path and name of a type expression like *web.AppsClient
+func qualifiedType(expr ast.Expr, imports map[string]string) (string, string) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	return imports[ident.Name], sel.Sel.Name
+}
@@ -0,0 +1,107 @@
+package policymaker
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const azureFixtureSource = "testdata/providers/terraform-provider-azurerm"
+
+func TestAzureClientIndex(t *testing.T) {
+	index, err := newAzureClientIndex(azureFixtureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		field string
+		want  string
+	}{
+		{"Web.AppServicesClient", "web.AppsClient"},
+		{"Web.AppServicePlansClient", "web.AppServicePlansClient"},
+		{"Resource.GroupsClient", "resources.GroupsClient"},
+	}
+	for _, c := range cases {
+		t.Run(c.field, func(t *testing.T) {
+			client, ok := index.services[c.field]
+			if !ok {
+				t.Fatalf("%s is not in the index", c.field)
+			}
+			if client.String() != c.want {
+				t.Errorf("got %s, want %s", client, c.want)
+			}
+		})
+	}
+}
+
+func TestAzureCallAnalyzer(t *testing.T) {
+	index, err := newAzureClientIndex(azureFixtureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]map[Phase][]string)
+	for _, service := range []string{"web", "resource"} {
+		analyzer, err := newAzureCallAnalyzer(filepath.Join(azureFixtureSource, "azurerm", "internal", "services", service), index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for path, operationsByPhase := range analyzer.OperationsByFile(isAzureResourceFile) {
+			got[filepath.Base(path)] = make(map[Phase][]string)
+			for phase, operations := range operationsByPhase {
+				for _, operation := range operations {
+					got[filepath.Base(path)][phase] = append(got[filepath.Base(path)][phase], fmt.Sprintf("%s:%d", operation.Name, operation.Position.Line))
+				}
+				sort.Strings(got[filepath.Base(path)][phase])
+			}
+		}
+	}
+
+	cases := []struct {
+		name  string
+		file  string
+		phase Phase
+		want  []string
+	}{
+		{"client assigned from the service field and the update it returns", "resource_arm_app_service.go", PhaseCreate, []string{"Microsoft.Web/sites/config/write:38", "Microsoft.Web/sites/read:20", "Microsoft.Web/sites/restart/action:45", "Microsoft.Web/sites/write:21"}},
+		{"list methods returning secrets", "resource_arm_app_service.go", PhaseRead, []string{"Microsoft.Web/sites/config/list/action:31", "Microsoft.Web/sites/read:30"}},
+		{"client passed into a helper", "resource_arm_app_service.go", PhaseUpdate, []string{"Microsoft.Web/sites/config/write:38", "Microsoft.Web/sites/restart/action:45"}},
+		{"delete", "resource_arm_app_service.go", PhaseDelete, []string{"Microsoft.Web/sites/delete:51"}},
+		{"call on the service field", "app_service_plan_data_source.go", PhaseRead, []string{"Microsoft.Web/serverfarms/read:15"}},
+		{"renamed client and idiosyncratic method", "resource_group_resource.go", PhaseCreate, []string{"Microsoft.Resources/subscriptions/resourceGroups/read:19", "Microsoft.Resources/subscriptions/resourceGroups/write:22"}},
+		{"long running delete", "resource_group_resource.go", PhaseDelete, []string{"Microsoft.Resources/subscriptions/resourceGroups/delete:36"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if operations := got[c.file][c.phase]; !reflect.DeepEqual(operations, c.want) {
+				t.Errorf("%s %s: got %v, want %v", c.file, c.phase, operations, c.want)
+			}
+		})
+	}
+	if _, ok := got["client.go"]; ok {
+		t.Error("client.go is not a resource file")
+	}
+}
+
+func TestAzureClientOperation(t *testing.T) {
+	cases := []struct {
+		client azureClient
+		method string
+		want   string
+	}{
+		{azureClient{Service: "compute", Package: "compute", Type: "VirtualMachinesClient"}, "CreateOrUpdateThenPoll", "Microsoft.Compute/virtualMachines/write"},
+		{azureClient{Service: "compute", Package: "compute", Type: "VirtualMachinesClient"}, "ListComplete", "Microsoft.Compute/virtualMachines/read"},
+		{azureClient{Service: "compute", Package: "compute", Type: "VirtualMachinesClient"}, "PowerOff", "Microsoft.Compute/virtualMachines/powerOff/action"},
+		{azureClient{Service: "storage", Package: "storage", Type: "AccountsClient"}, "ListKeys", "Microsoft.Storage/storageAccounts/listKeys/action"},
+		{azureClient{Service: "keyvault", Package: "keyvault", Type: "VaultsClient"}, "Delete", "Microsoft.KeyVault/vaults/delete"},
+		{azureClient{Service: "web", Package: "web", Type: "AppsClient"}, "ListApplicationSettings", "Microsoft.Web/sites/config/list/action"},
+	}
+	for _, c := range cases {
+		t.Run(c.client.String()+"."+c.method, func(t *testing.T) {
+			if got := c.client.Operation(c.method); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package policymaker
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/*
+AzureExtractor builds the mapping of terraform-provider-azurerm from the calls its resources make
+on the Azure SDK clients, as resource provider operations like Microsoft.Web/sites/write
+*/
+type AzureExtractor struct{}
+
+// NewAzureExtractor is the Constructor for AzureExtractor
+func NewAzureExtractor() *AzureExtractor {
+	return &AzureExtractor{}
+}
+
+/*
+Extract builds the mapping of the provider source in sourceDir. The clients are declared in other
+packages than the resources using them, so those are collected from the whole source first.
+*/
+func (e *AzureExtractor) Extract(sourceDir string) (*Mapping, error) {
+	index, err := newAzureClientIndex(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	extractor := &packageExtractor{
+		isResourceFile: isAzureResourceFile,
+		resourceName:   azureResourceName,
+		newAnalyzer: func(dir string) (callAnalyzer, error) {
+			return newAzureCallAnalyzer(dir, index)
+		},
+	}
+	return extractor.extract(sourceDir)
+}
+
+// helper function for validating that a file is a resource or data source, which azurerm may also name by suffix
+func isAzureResourceFile(fileName string) bool {
+	if strings.HasSuffix(fileName, "test.go") {
+		return false
+	}
+	return isTerraformResourceFile(fileName) || strings.HasSuffix(fileName, "_data_source.go") || strings.HasSuffix(fileName, "_resource.go")
+}
+
+/*
+azureResourceName returns the mapping key of a resource file. The files either abbreviate the
+provider, e.g. resource_arm_app_service.go, or put the mode last, e.g. resource_group_resource.go
+for azurerm_resource_group.
+*/
+func azureResourceName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".go")
+	var mode, typeName string
+	switch {
+	case strings.HasSuffix(name, "_data_source"):
+		mode, typeName = "data_source", strings.TrimSuffix(name, "_data_source")
+	case strings.HasSuffix(name, "_resource"):
+		mode, typeName = "resource", strings.TrimSuffix(name, "_resource")
+	case strings.HasPrefix(name, "data_source_"):
+		mode, typeName = "data_source", strings.TrimPrefix(name, "data_source_")
+	case strings.HasPrefix(name, "resource_"):
+		mode, typeName = "resource", strings.TrimPrefix(name, "resource_")
+	default:
+		return name
+	}
+	return resourceKey(mode, strings.TrimPrefix(typeName, "arm_"), azurermProvider)
+}
@@ -0,0 +1,51 @@
+package policymaker
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SubscriptionScopePlaceholder is the assignable scope of a role definition when the subscription is not known
+const SubscriptionScopePlaceholder = "/subscriptions/00000000-0000-0000-0000-000000000000"
+
+/*
+RoleDefinition is an Azure custom role definition, in the format az role definition create
+--role-definition accepts
+*/
+type RoleDefinition struct {
+	Name             string   `json:"Name"`
+	IsCustom         bool     `json:"IsCustom"`
+	Description      string   `json:"Description"`
+	Actions          []string `json:"Actions"`
+	NotActions       []string `json:"NotActions"`
+	DataActions      []string `json:"DataActions"`
+	NotDataActions   []string `json:"NotDataActions"`
+	AssignableScopes []string `json:"AssignableScopes"`
+}
+
+// NewRoleDefinition is the Constructor for RoleDefinition
+func NewRoleDefinition(provider string) *RoleDefinition {
+	return &RoleDefinition{
+		Name:             fmt.Sprintf("Terraform %s", provider),
+		IsCustom:         true,
+		Description:      fmt.Sprintf("Operations needed by terraform to manage the %s resources of a configuration, generated by terraform-policymaker", provider),
+		Actions:          []string{},
+		NotActions:       []string{},
+		DataActions:      []string{},
+		NotDataActions:   []string{},
+		AssignableScopes: []string{},
+	}
+}
+
+// SubscriptionScope returns the scope of a subscription, e.g. /subscriptions/<id>, or a placeholder if it is empty
+func SubscriptionScope(subscriptionID string) string {
+	if subscriptionID == "" {
+		return SubscriptionScopePlaceholder
+	}
+	return "/subscriptions/" + subscriptionID
+}
+
+// JSON returns the role definition as indented JSON
+func (r *RoleDefinition) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
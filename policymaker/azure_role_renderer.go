@@ -0,0 +1,52 @@
+package policymaker
+
+import (
+	"fmt"
+)
+
+/*
+AzureRenderer renders the custom role definition of the azurerm resources as azurerm_role.json,
+which can be assigned within the subscription they are deployed to
+*/
+type AzureRenderer struct{}
+
+// NewAzureRenderer is the Constructor for AzureRenderer
+func NewAzureRenderer() *AzureRenderer {
+	return &AzureRenderer{}
+}
+
+// OutputPatterns returns the pattern of the role definition
+func (r *AzureRenderer) OutputPatterns() []string {
+	return []string{fmt.Sprintf("%s_role.json", azurermProvider)}
+}
+
+// Render returns the role definition
+func (r *AzureRenderer) Render(actions []*ResourceActions, values *PlanValues) ([]*Document, error) {
+	role := r.BuildRoleDefinition(actions, values)
+	content, err := role.JSON()
+	if err != nil {
+		return nil, &PolicyError{Op: "marshal role definition", Err: err}
+	}
+	fileName := fmt.Sprintf("%s_role.json", azurermProvider)
+	if role.AssignableScopes[0] == SubscriptionScopePlaceholder {
+		fmt.Printf("######### Warning: the subscription is not known, replace %s in the assignable scopes of %s\n", SubscriptionScopePlaceholder, fileName)
+	}
+	return []*Document{
+		{FileName: fileName, Content: content, Summary: fmt.Sprintf("%d actions", len(role.Actions))},
+	}, nil
+}
+
+// BuildRoleDefinition builds a role definition with the operations of every resource as its actions
+func (r *AzureRenderer) BuildRoleDefinition(actions []*ResourceActions, values *PlanValues) *RoleDefinition {
+	fmt.Println("######### New Role Definition")
+	actionsSet := make(map[string]bool)
+	for _, resourceActions := range actions {
+		for _, action := range resourceActions.Actions {
+			actionsSet[action] = true
+		}
+	}
+	role := NewRoleDefinition(azurermProvider)
+	role.Actions = append(role.Actions, sortedKeys(actionsSet)...)
+	role.AssignableScopes = append(role.AssignableScopes, SubscriptionScope(values.SubscriptionID))
+	return role
+}
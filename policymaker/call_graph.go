@@ -0,0 +1,410 @@
+package policymaker
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+callGraph type checks a single package of the provider source code, and records which
+functions refer to which, so that the operations reachable from each lifecycle function of a
+schema.Resource can be collected. What counts as an operation differs per provider, so it is
+left to findOperations.
+*/
+type callGraph struct {
+	fset  *token.FileSet
+	info  *types.Info
+	pkg   *types.Package
+	files map[string]*ast.File
+	decls map[*types.Func]*ast.FuncDecl
+	// calls holds the operations and package functions used directly by each function
+	calls map[*types.Func]*callSet
+	// findOperations records the operations invoked within a node, with addOperation
+	findOperations func(node ast.Node, operations map[string]token.Pos)
+	// operationPhases classifies an operation in a file without a schema.Resource literal
+	operationPhases func(operation string) []Phase
+}
+
+// callSet holds the operations invoked (and where) and the package functions referred to by a function body
+type callSet struct {
+	operations map[string]token.Pos
+	references map[*types.Func]bool
+}
+
+func newCallSet() *callSet {
+	return &callSet{
+		operations: make(map[string]token.Pos),
+		references: make(map[*types.Func]bool),
+	}
+}
+
+// operationCall is an operation found in the provider source, along with the first place it is invoked
+type operationCall struct {
+	Name     string
+	Position token.Position
+}
+
+// helper function that records an operation invoked at pos, keeping the first place it is invoked
+func addOperation(operations map[string]token.Pos, operation string, pos token.Pos) {
+	if existing, ok := operations[operation]; !ok || pos < existing {
+		operations[operation] = pos
+	}
+}
+
+/*
+These are the fields of a schema.Resource that hold the function terraform calls during each
+phase of the resource lifecycle
+*/
+var resourcePhaseFields = map[string]Phase{
+	"Create":               PhaseCreate,
+	"CreateContext":        PhaseCreate,
+	"CreateWithoutTimeout": PhaseCreate,
+	"Read":                 PhaseRead,
+	"ReadContext":          PhaseRead,
+	"ReadWithoutTimeout":   PhaseRead,
+	"Exists":               PhaseRead,
+	"CustomizeDiff":        PhaseRead,
+	"Update":               PhaseUpdate,
+	"UpdateContext":        PhaseUpdate,
+	"UpdateWithoutTimeout": PhaseUpdate,
+	"Delete":               PhaseDelete,
+	"DeleteContext":        PhaseDelete,
+	"DeleteWithoutTimeout": PhaseDelete,
+}
+
+// These are the methods of a plugin framework resource terraform calls during each phase
+var frameworkPhaseMethods = map[string]Phase{
+	"Create": PhaseCreate,
+	"Read":   PhaseRead,
+	"Update": PhaseUpdate,
+	"Delete": PhaseDelete,
+}
+
+// stubImporter satisfies imports with empty packages, as the provider dependencies are not available
+type stubImporter map[string]*types.Package
+
+func (s stubImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := s[path]; ok {
+		return pkg, nil
+	}
+	pkg := types.NewPackage(path, filepath.Base(path))
+	pkg.MarkComplete()
+	s[path] = pkg
+	return pkg, nil
+}
+
+/*
+newCallGraph parses and type checks all non test files in a directory. Type errors
+caused by the missing dependencies are expected and ignored, since only objects declared
+in the package itself need to be resolved. The calls are collected by collectCalls, once
+findOperations is set.
+*/
+func newCallGraph(dir string) (*callGraph, error) {
+	fset := token.NewFileSet()
+	filter := func(file os.FileInfo) bool {
+		return !strings.HasSuffix(file.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, 0)
+	if err != nil {
+		return nil, err
+	}
+	// a directory may contain more than one package (e.g. main.go), so pick the largest
+	var astPkg *ast.Package
+	for _, p := range pkgs {
+		if astPkg == nil || len(p.Files) > len(astPkg.Files) {
+			astPkg = p
+		}
+	}
+	g := &callGraph{
+		fset:  fset,
+		files: make(map[string]*ast.File),
+		decls: make(map[*types.Func]*ast.FuncDecl),
+		calls: make(map[*types.Func]*callSet),
+		info: &types.Info{
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		},
+	}
+	if astPkg == nil {
+		return g, nil
+	}
+	var files []*ast.File
+	for name, file := range astPkg.Files {
+		g.files[name] = file
+		files = append(files, file)
+	}
+	conf := types.Config{
+		Importer: stubImporter{},
+		Error:    func(err error) {},
+	}
+	g.pkg, _ = conf.Check(astPkg.Name, fset, files, g.info)
+
+	g.collectDecls()
+	return g, nil
+}
+
+/*
+OperationsByFile returns, for every file accepted by the filter, the operations reachable from
+each Create/Read/Update/Delete function of the schema.Resource declared in that file. Files
+without such a literal fall back to all the operations reachable from the functions they
+declare, classified by operationPhases.
+*/
+func (g *callGraph) OperationsByFile(filter func(string) bool) map[string]map[Phase][]operationCall {
+	result := make(map[string]map[Phase][]operationCall)
+	for name, file := range g.files {
+		if !filter(filepath.Base(name)) {
+			continue
+		}
+		declared := newCallSet()
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok {
+				if fn, ok := g.info.Defs[fd.Name].(*types.Func); ok {
+					declared.references[fn] = true
+				}
+			}
+		}
+		if operationsByPhase := g.operationsByPhase(g.phaseCalls(file), declared); len(operationsByPhase) > 0 {
+			result[name] = operationsByPhase
+		}
+	}
+	return result
+}
+
+/*
+OperationsByFunc returns, for every named function of the package, the operations reachable from
+each lifecycle function of the resource it returns: the Create/Read/Update/Delete functions of a
+schema.Resource literal, or else the Create/Read/Update/Delete methods of a plugin framework
+resource it instantiates. Functions returning neither fall back to all the operations reachable
+from them, classified by operationPhases.
+*/
+func (g *callGraph) OperationsByFunc(names []string) map[string]map[Phase][]operationCall {
+	funcs := make(map[string]*types.Func)
+	for fn, decl := range g.decls {
+		if decl.Recv == nil {
+			funcs[fn.Name()] = fn
+		}
+	}
+	result := make(map[string]map[Phase][]operationCall)
+	for _, name := range names {
+		fn := funcs[name]
+		if fn == nil {
+			continue
+		}
+		phaseCalls := g.phaseCalls(g.decls[fn])
+		if len(phaseCalls) == 0 {
+			phaseCalls = g.frameworkPhaseCalls(g.decls[fn])
+		}
+		reachable := newCallSet()
+		reachable.references[fn] = true
+		if operationsByPhase := g.operationsByPhase(phaseCalls, reachable); len(operationsByPhase) > 0 {
+			result[name] = operationsByPhase
+		}
+	}
+	return result
+}
+
+/*
+helper function that collects the operations reachable from the lifecycle functions of each phase,
+or from fallback if there are none, classified by operationPhases
+*/
+func (g *callGraph) operationsByPhase(phaseCalls map[Phase]*callSet, fallback *callSet) map[Phase][]operationCall {
+	operationsByPhase := make(map[Phase][]operationCall)
+	if len(phaseCalls) > 0 {
+		for phase, calls := range phaseCalls {
+			if operations := g.reachableOperations(calls); len(operations) > 0 {
+				operationsByPhase[phase] = operations
+			}
+		}
+		return operationsByPhase
+	}
+	for _, operation := range g.reachableOperations(fallback) {
+		for _, phase := range g.operationPhases(operation.Name) {
+			operationsByPhase[phase] = append(operationsByPhase[phase], operation)
+		}
+	}
+	return operationsByPhase
+}
+
+/*
+phaseCalls finds the schema.Resource literals in a file or function and returns what the function
+assigned to each lifecycle field uses. The function may be declared elsewhere or be a function literal.
+*/
+func (g *callGraph) phaseCalls(node ast.Node) map[Phase]*callSet {
+	result := make(map[Phase]*callSet)
+	ast.Inspect(node, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		if sel, ok := lit.Type.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Resource" {
+			return true
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			phase, ok := resourcePhaseFields[key.Name]
+			if !ok {
+				continue
+			}
+			if result[phase] == nil {
+				result[phase] = newCallSet()
+			}
+			g.inspectCalls(kv.Value, result[phase])
+		}
+		return true
+	})
+	return result
+}
+
+/*
+frameworkPhaseCalls finds the types a function instantiates, e.g. &bucketResource{}, and returns
+their Create/Read/Update/Delete methods, which is how a plugin framework resource implements its
+lifecycle
+*/
+func (g *callGraph) frameworkPhaseCalls(decl *ast.FuncDecl) map[Phase]*callSet {
+	result := make(map[Phase]*callSet)
+	ast.Inspect(decl, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		ident, ok := lit.Type.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		typeName, ok := g.objectOf(ident).(*types.TypeName)
+		if !ok {
+			return true
+		}
+		methods := types.NewMethodSet(types.NewPointer(typeName.Type()))
+		for i := 0; i < methods.Len(); i++ {
+			method, ok := methods.At(i).Obj().(*types.Func)
+			if !ok || g.decls[method] == nil {
+				continue
+			}
+			phase, ok := frameworkPhaseMethods[method.Name()]
+			if !ok {
+				continue
+			}
+			if result[phase] == nil {
+				result[phase] = newCallSet()
+			}
+			result[phase].references[method] = true
+		}
+		return true
+	})
+	return result
+}
+
+/*
+helper function for walking the reference graph and collecting all invoked operations, sorted by
+name. Operations invoked in several places are attributed to the first of them.
+*/
+func (g *callGraph) reachableOperations(start *callSet) []operationCall {
+	visited := make(map[*types.Func]bool)
+	operationsSet := make(map[string]token.Pos)
+	var visit func(calls *callSet)
+	visit = func(calls *callSet) {
+		for operation, pos := range calls.operations {
+			addOperation(operationsSet, operation, pos)
+		}
+		for ref := range calls.references {
+			if !visited[ref] && g.calls[ref] != nil {
+				visited[ref] = true
+				visit(g.calls[ref])
+			}
+		}
+	}
+	visit(start)
+	operationsList := make([]operationCall, 0, len(operationsSet))
+	for operation, pos := range operationsSet {
+		operationsList = append(operationsList, operationCall{Name: operation, Position: g.fset.Position(pos)})
+	}
+	sort.Slice(operationsList, func(i, j int) bool {
+		return operationsList[i].Name < operationsList[j].Name
+	})
+	return operationsList
+}
+
+func (g *callGraph) collectDecls() {
+	for _, file := range g.files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			if fn, ok := g.info.Defs[fd.Name].(*types.Func); ok {
+				g.decls[fn] = fd
+			}
+		}
+	}
+}
+
+func (g *callGraph) collectCalls() {
+	for fn, decl := range g.decls {
+		calls := newCallSet()
+		g.inspectCalls(decl.Body, calls)
+		g.calls[fn] = calls
+	}
+}
+
+// helper function that records the operations and package functions used within a node
+func (g *callGraph) inspectCalls(node ast.Node, calls *callSet) {
+	g.findOperations(node, calls.operations)
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if ref, ok := g.info.Uses[ident].(*types.Func); ok && g.decls[ref] != nil {
+				calls.references[ref] = true
+			}
+		}
+		return true
+	})
+}
+
+// helper function that resolves the package function being called, if any
+func (g *callGraph) calledFunc(call *ast.CallExpr) *types.Func {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := g.info.Uses[ident].(*types.Func)
+	return fn
+}
+
+func (g *callGraph) objectOf(ident *ast.Ident) types.Object {
+	if obj := g.info.Defs[ident]; obj != nil {
+		return obj
+	}
+	return g.info.Uses[ident]
+}
+
+// helper function that lists the parameter names of a function in order
+func paramIdents(ft *ast.FuncType) []*ast.Ident {
+	var idents []*ast.Ident
+	for _, field := range ft.Params.List {
+		if len(field.Names) == 0 {
+			idents = append(idents, nil)
+			continue
+		}
+		idents = append(idents, field.Names...)
+	}
+	return idents
+}
@@ -0,0 +1,140 @@
+package policymaker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+/*
+DefaultGracePeriod is how long a program may take to stop after it is interrupted, before it is
+killed. Terraform needs the time to release the lock on its state.
+*/
+const DefaultGracePeriod = 10 * time.Second
+
+/*
+Command describes a program to run. The program is started directly rather than through a shell,
+so arguments are passed as they are and paths containing spaces need no quoting.
+*/
+type Command struct {
+	// Args is the program followed by its arguments, e.g. []string{"terraform", "init"}
+	Args []string
+	// Dir is the working directory of the program, or the current one if empty
+	Dir string
+	// Env holds extra KEY=value pairs on top of the current environment
+	Env []string
+	// Timeout stops the program after the given duration, or never if it is zero
+	Timeout time.Duration
+	// Verbose streams the output of the program to stdout and stderr while it runs
+	Verbose bool
+	// GracePeriod is how long the program may take to stop once interrupted, or DefaultGracePeriod if zero
+	GracePeriod time.Duration
+}
+
+// CommandResult is what a command wrote, and the status it exited with
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// NewCommand is the constructor for Command
+func NewCommand(args ...string) *Command {
+	return &Command{Args: args}
+}
+
+/*
+Run runs the command until it exits, the timeout expires or ctx is cancelled. If the command
+could not be started or exited unsuccessfully, the result is returned along with a CommandError.
+A command that is stopped is interrupted first, and only killed if it is still running after the
+grace period. The exit code is -1 if the command did not exit by itself.
+*/
+func (c *Command) Run(ctx context.Context) (*CommandResult, error) {
+	if len(c.Args) == 0 {
+		return nil, &CommandError{Err: errors.New("no command given"), ExitCode: -1}
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	cmd := exec.Command(c.Args[0], c.Args[1:]...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if c.Verbose {
+		cmd.Stdout = io.MultiWriter(&stdout, os.Stdout)
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+	}
+	err := c.wait(ctx, cmd)
+	result := &CommandResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: -1,
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return result, &CommandError{Command: c.String(), Dir: c.Dir, Stderr: result.Stderr, ExitCode: result.ExitCode, Err: err}
+	}
+	return result, nil
+}
+
+// helper function that starts a command and waits for it, interrupting it when ctx is done
+func (c *Command) wait(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	gracePeriod := c.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = DefaultGracePeriod
+	}
+	// programs cannot be interrupted on every platform, those are killed right away
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// String returns the command line, with arguments that contain spaces quoted
+func (c *Command) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			args[i] = fmt.Sprintf("%q", arg)
+		}
+	}
+	return strings.Join(args, " ")
+}
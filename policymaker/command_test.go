@@ -0,0 +1,107 @@
+package policymaker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandRun(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name     string
+		command  *Command
+		stdout   string
+		stderr   string
+		exitCode int
+		failed   bool
+	}{
+		{
+			"success",
+			&Command{Args: []string{"sh", "-c", "echo out; echo err >&2"}},
+			"out\n", "err\n", 0, false,
+		},
+		{
+			"non-zero exit",
+			&Command{Args: []string{"sh", "-c", "echo partial; echo 'Error: no state' >&2; exit 3"}},
+			"partial\n", "Error: no state\n", 3, true,
+		},
+		{
+			"working directory and environment",
+			&Command{Args: []string{"sh", "-c", `echo "$(pwd) $GREETING"`}, Dir: dir, Env: []string{"GREETING=hello"}},
+			dir + " hello\n", "", 0, false,
+		},
+		{
+			"program that does not exist",
+			&Command{Args: []string{"terraform-policymaker-missing-program"}},
+			"", "", -1, true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := c.command.Run(context.Background())
+			if (err != nil) != c.failed {
+				t.Fatalf("got error %v, want failure %v", err, c.failed)
+			}
+			if result.Stdout != c.stdout || result.Stderr != c.stderr || result.ExitCode != c.exitCode {
+				t.Errorf("got %q, %q and exit code %d, want %q, %q and %d", result.Stdout, result.Stderr, result.ExitCode, c.stdout, c.stderr, c.exitCode)
+			}
+			var commandErr *CommandError
+			if c.failed && (!errors.As(err, &commandErr) || commandErr.ExitCode != c.exitCode || commandErr.Stderr != c.stderr) {
+				t.Errorf("got %#v, want a CommandError with the exit code and stderr", err)
+			}
+		})
+	}
+}
+
+func TestCommandErrorMessage(t *testing.T) {
+	_, err := NewCommand("sh", "-c", "echo 'Error: Error acquiring the state lock' >&2; exit 1").Run(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	message := err.Error()
+	for _, want := range []string{`"sh -c \"echo 'Error: Error acquiring the state lock' >&2; exit 1\""`, "exited with status 1", "Error acquiring the state lock"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("%q does not include %q", message, want)
+		}
+	}
+}
+
+func TestCommandIsInterruptedBeforeItIsKilled(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "interrupted")
+	// like terraform, the program cleans up when it is interrupted
+	command := NewCommand("sh", "-c", `trap 'echo done > "$MARKER"; exit 130' INT; while :; do sleep 0.1; done`)
+	command.Env = []string{"MARKER=" + marker}
+	command.Timeout = 300 * time.Millisecond
+	result, err := command.Run(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the timeout", err)
+	}
+	if result.ExitCode != 130 {
+		t.Errorf("got exit code %d, want the program to exit by itself", result.ExitCode)
+	}
+	if dat, err := ioutil.ReadFile(marker); err != nil || strings.TrimSpace(string(dat)) != "done" {
+		t.Errorf("the program was not interrupted: %v", err)
+	}
+}
+
+func TestCommandIsKilledAfterGracePeriod(t *testing.T) {
+	command := NewCommand("sh", "-c", `trap '' INT; while :; do sleep 0.1; done`)
+	command.Timeout = 200 * time.Millisecond
+	command.GracePeriod = 200 * time.Millisecond
+	start := time.Now()
+	result, err := command.Run(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the timeout", err)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("got exit code %d, want -1 for a killed program", result.ExitCode)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the program kept running for %s", elapsed)
+	}
+}
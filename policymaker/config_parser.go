@@ -0,0 +1,422 @@
+package policymaker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/tidwall/gjson"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// configFileSchema describes the blocks of a terraform configuration file that are needed
+var configFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "provider", LabelNames: []string{"name"}},
+		{Type: "terraform"},
+	},
+}
+
+// terraformBlockSchema describes the blocks of a terraform block that are needed
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "required_providers"},
+	},
+}
+
+// defaultProviderNamespace is the registry namespace of providers without an explicit source, e.g. aws
+const defaultProviderNamespace = "registry.terraform.io/hashicorp"
+
+/*
+ConfigParser reads the resources of a terraform configuration directly from its .tf files,
+so that a policy can be generated without credentials, backend access or running terraform.
+Child modules are followed as long as their source is a local path.
+*/
+type ConfigParser struct {
+	Path      string
+	parser    *hclparse.Parser
+	resources []*PlannedResource
+	providers map[string]bool
+	region    string
+	// subscriptionID is the subscription_id of the root module's azurerm provider, if it is a constant
+	subscriptionID string
+	// moduleDirs are the absolute directories of the root module and the local modules it includes
+	moduleDirs map[string]bool
+	// quiet leaves out the progress messages, when the configuration is only parsed for its modules
+	quiet  bool
+	parsed bool
+	err    error
+}
+
+// NewConfigParser is the constructor for ConfigParser
+func NewConfigParser(path string) *ConfigParser {
+	return &ConfigParser{
+		Path:       path,
+		parser:     hclparse.NewParser(),
+		providers:  make(map[string]bool),
+		moduleDirs: make(map[string]bool),
+	}
+}
+
+/*
+GetResources gets all unique resources declared in the configuration
+*/
+func (c *ConfigParser) GetResources(ctx context.Context) ([]*Resource, error) {
+	if err := c.parse(); err != nil {
+		return nil, err
+	}
+	resources := make([]*Resource, len(c.resources))
+	for i, resource := range c.resources {
+		resources[i] = resource.Resource
+	}
+	return NewResourceSet(resources...).List(), nil
+}
+
+/*
+GetPlanValues gets the values of every resource in the configuration. Only attributes that are
+set to a constant are known, since nothing is evaluated.
+*/
+func (c *ConfigParser) GetPlanValues(ctx context.Context) (*PlanValues, error) {
+	if err := c.parse(); err != nil {
+		return nil, err
+	}
+	return &PlanValues{
+		Region:         c.region,
+		SubscriptionID: c.subscriptionID,
+		Resources:      c.resources,
+	}, nil
+}
+
+/*
+GetResourcePhases gets the lifecycle phases each resource needs permissions for. Without a plan
+it is not known what will change, so managed resources need every phase.
+*/
+func (c *ConfigParser) GetResourcePhases(ctx context.Context) (map[string][]Phase, error) {
+	resources, err := c.GetResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	phasesMap := make(map[string][]Phase)
+	for _, resource := range resources {
+		phasesMap[resource.ToString()] = AllPhases
+		if resource.Mode == ModeData {
+			phasesMap[resource.ToString()] = []Phase{PhaseRead}
+		}
+	}
+	return phasesMap, nil
+}
+
+/*
+GetProviders gets the addresses of every provider configured in a provider block, or used by
+a resource, in the configuration
+*/
+func (c *ConfigParser) GetProviders(ctx context.Context) ([]string, error) {
+	if err := c.parse(); err != nil {
+		return nil, err
+	}
+	return sortedKeys(c.providers), nil
+}
+
+/*
+GetProviderVersion gets the version of a provider selected by terraform init, from the lock file
+in Path. The version is empty if the configuration has not been initialized.
+*/
+func (c *ConfigParser) GetProviderVersion(ctx context.Context, provider string) (string, error) {
+	return lockFileVersion(c.Path, provider)
+}
+
+/*
+ModuleDirs gets the absolute directories of the root module and of every local module it includes,
+which may be outside of Path, e.g. ../modules/network
+*/
+func (c *ConfigParser) ModuleDirs(ctx context.Context) ([]string, error) {
+	if err := c.parse(); err != nil {
+		return nil, err
+	}
+	return sortedKeys(c.moduleDirs), nil
+}
+
+func (c *ConfigParser) parse() error {
+	if c.parsed {
+		return c.err
+	}
+	c.parsed = true
+	if !c.quiet {
+		fmt.Printf("Parsing configuration in %s\n", c.Path)
+	}
+	c.resources, c.err = c.parseModule(c.Path, "", nil)
+	return c.err
+}
+
+/*
+helper function that lists the configuration files of a module directory, both in the native syntax
+and in JSON, with the override files (override.tf, *_override.tf and their JSON forms) listed apart
+*/
+func configFiles(dir string) ([]string, []string, error) {
+	var files, overrides []string
+	for _, pattern := range []string{"*.tf", "*.tf.json"} {
+		paths, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range paths {
+			name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".json"), ".tf")
+			if name == "override" || strings.HasSuffix(name, "_override") {
+				overrides = append(overrides, path)
+			} else {
+				files = append(files, path)
+			}
+		}
+	}
+	return files, overrides, nil
+}
+
+// helper function that parses a configuration file in the native syntax or in JSON
+func (c *ConfigParser) parseFile(path string) (*hcl.BodyContent, error) {
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(path, ".json") {
+		file, diags = c.parser.ParseJSONFile(path)
+	} else {
+		file, diags = c.parser.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, &PlanError{Op: "parse configuration", Path: path, Err: diags}
+	}
+	content, _, _ := file.Body.PartialContent(configFileSchema)
+	return content, nil
+}
+
+/*
+helper function that parses all the configuration files in a module directory, recursing into local
+child modules. Override files are applied after the other files: they can only change blocks that
+exist already, so they change the provider settings and module sources but add no resources. The
+directories of the modules that include this one are passed along, to stop at a module that
+includes itself.
+*/
+func (c *ConfigParser) parseModule(dir string, modulePath string, ancestors []string) ([]*PlannedResource, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, &PlanError{Op: "find module", Path: dir, Err: err}
+	}
+	for _, ancestor := range ancestors {
+		if ancestor == absDir {
+			return nil, &PlanError{Op: "follow module " + modulePath, Path: dir, Err: fmt.Errorf("the module includes itself")}
+		}
+	}
+	ancestors = append(ancestors, absDir)
+	c.moduleDirs[absDir] = true
+	paths, overridePaths, err := configFiles(dir)
+	if err != nil {
+		return nil, &PlanError{Op: "list configuration files", Path: dir, Err: err}
+	}
+	if len(paths) == 0 {
+		return nil, &PlanError{Op: "list configuration files", Path: dir, Err: fmt.Errorf("no .tf files found")}
+	}
+	var contents, overrides []*hcl.BodyContent
+	for _, path := range paths {
+		content, err := c.parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+	for _, path := range overridePaths {
+		content, err := c.parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, content)
+	}
+	// the provider addresses of a module may be declared in any of its files
+	addresses := requiredProviders(append(contents, overrides...))
+	var resources []*PlannedResource
+	var moduleNames []string
+	moduleSources := make(map[string]string)
+	for _, content := range contents {
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "resource", "data":
+				resource := c.parseResource(block, modulePath, addresses)
+				c.providers[resource.Provider] = true
+				resources = append(resources, resource)
+			case "provider":
+				c.parseProvider(block, modulePath, addresses)
+			case "module":
+				moduleNames = append(moduleNames, block.Labels[0])
+				attributes, _ := block.Body.JustAttributes()
+				moduleSources[block.Labels[0]], _ = constantString(attributes["source"])
+			}
+		}
+	}
+	for _, content := range overrides {
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "provider":
+				c.parseProvider(block, modulePath, addresses)
+			case "module":
+				attributes, _ := block.Body.JustAttributes()
+				if source, ok := constantString(attributes["source"]); ok {
+					moduleSources[block.Labels[0]] = source
+				}
+			}
+		}
+	}
+	for _, name := range moduleNames {
+		source := moduleSources[name]
+		if !(strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")) {
+			if c.quiet {
+				continue
+			}
+			fmt.Printf("Skipping module %s, only local module sources are supported\n", name)
+			continue
+		}
+		childPath := "module." + name
+		if modulePath != "" {
+			childPath = modulePath + "." + childPath
+		}
+		childResources, err := c.parseModule(filepath.Join(dir, source), childPath, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, childResources...)
+	}
+	return resources, nil
+}
+
+/*
+helper function that records the provider of a provider block. Only the default (unaliased) aws
+provider of the root module determines the region, and only its azurerm provider the subscription.
+*/
+func (c *ConfigParser) parseProvider(block *hcl.Block, modulePath string, addresses map[string]string) {
+	address := localProviderAddress(block.Labels[0], addresses)
+	c.providers[address] = true
+	attributes, _ := block.Body.JustAttributes()
+	if _, aliased := attributes["alias"]; aliased || modulePath != "" {
+		return
+	}
+	if providerName(address) == awsProvider {
+		if region, ok := constantString(attributes["region"]); ok {
+			c.region = region
+		}
+	}
+	if providerName(address) == azurermProvider {
+		if subscriptionID, ok := constantString(attributes["subscription_id"]); ok {
+			c.subscriptionID = subscriptionID
+		}
+	}
+}
+
+func (c *ConfigParser) parseResource(block *hcl.Block, modulePath string, addresses map[string]string) *PlannedResource {
+	mode := "managed"
+	if block.Type == "data" {
+		mode = "data"
+	}
+	resource := NewResource(block.Labels[0], mode)
+	resource.Module = modulePath
+	// the provider is implied by the resource type, unless it is set explicitly, e.g. provider = aws.west
+	localName := strings.Split(resource.Type, "_")[0]
+	attributes, _ := block.Body.JustAttributes()
+	if provider, ok := attributes["provider"]; ok {
+		if traversal, diags := hcl.AbsTraversalForExpr(provider.Expr); !diags.HasErrors() {
+			localName = traversal.RootName()
+		}
+	}
+	resource.Provider = localProviderAddress(localName, addresses)
+	address := resource.Type + "." + block.Labels[1]
+	if block.Type == "data" {
+		address = "data." + address
+	}
+	if modulePath != "" {
+		address = modulePath + "." + address
+	}
+	return &PlannedResource{
+		Resource: resource,
+		Address:  address,
+		Values:   constantValues(attributes),
+	}
+}
+
+/*
+requiredProviders maps the local names in the required_providers blocks of a module to the
+addresses of their sources, e.g. aws = { source = "hashicorp/aws" } to registry.terraform.io/hashicorp/aws
+*/
+func requiredProviders(contents []*hcl.BodyContent) map[string]string {
+	addresses := make(map[string]string)
+	for _, content := range contents {
+		for _, block := range content.Blocks.OfType("terraform") {
+			terraformContent, _, _ := block.Body.PartialContent(terraformBlockSchema)
+			for _, requiredProviders := range terraformContent.Blocks {
+				attributes, _ := requiredProviders.Body.JustAttributes()
+				for name, attribute := range attributes {
+					// the legacy form only holds a version constraint, e.g. aws = "~> 3.0"
+					value, diags := attribute.Expr.Value(nil)
+					if diags.HasErrors() || !value.Type().IsObjectType() || !value.Type().HasAttribute("source") {
+						continue
+					}
+					source := value.GetAttr("source")
+					if source.IsKnown() && !source.IsNull() && source.Type() == cty.String {
+						addresses[name] = sourceAddress(source.AsString())
+					}
+				}
+			}
+		}
+	}
+	return addresses
+}
+
+// helper function that resolves the local name of a provider to its address
+func localProviderAddress(localName string, addresses map[string]string) string {
+	if address, ok := addresses[localName]; ok {
+		return address
+	}
+	return defaultProviderNamespace + "/" + localName
+}
+
+// helper function that expands a provider source like hashicorp/aws to its full address
+func sourceAddress(source string) string {
+	switch len(strings.Split(source, "/")) {
+	case 1:
+		return defaultProviderNamespace + "/" + source
+	case 2:
+		return "registry.terraform.io/" + source
+	}
+	return source
+}
+
+// helper function that converts every attribute set to a constant into a JSON object
+func constantValues(attributes hcl.Attributes) gjson.Result {
+	values := make(map[string]cty.Value)
+	for name, attribute := range attributes {
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() || !value.IsWhollyKnown() || value.IsNull() {
+			continue
+		}
+		values[name] = value
+	}
+	object := cty.ObjectVal(values)
+	bytes, err := ctyjson.Marshal(object, object.Type())
+	if err != nil {
+		return gjson.Result{}
+	}
+	return gjson.ParseBytes(bytes)
+}
+
+// helper function that returns the value of an attribute if it is set to a constant string
+func constantString(attribute *hcl.Attribute) (string, bool) {
+	if attribute == nil {
+		return "", false
+	}
+	value, diags := attribute.Expr.Value(nil)
+	if diags.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		return "", false
+	}
+	return value.AsString(), true
+}
@@ -0,0 +1,109 @@
+package policymaker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConfigParser(t *testing.T) {
+	cases := []struct {
+		name      string
+		path      string
+		resources []string
+		region    string
+	}{
+		{
+			"local module",
+			"../test",
+			[]string{
+				"aws_ecr_repository (registry.terraform.io/hashicorp/aws)",
+				"module.a.aws_s3_bucket (registry.terraform.io/hashicorp/aws)",
+				"module.a.data.aws_caller_identity (registry.terraform.io/hashicorp/aws)",
+			},
+			"us-west-2",
+		},
+		{
+			"nested local modules",
+			"testdata/configs/nested",
+			[]string{
+				"module.network.aws_vpc (registry.terraform.io/hashicorp/aws)",
+				"module.network.module.subnet.aws_subnet (registry.terraform.io/hashicorp/aws)",
+			},
+			"",
+		},
+		{
+			"aliased provider",
+			"testdata/configs/aliased",
+			[]string{"aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+			"us-west-2",
+		},
+		{
+			"JSON syntax",
+			"testdata/configs/json",
+			[]string{"aws_sns_topic (registry.terraform.io/hashicorp/aws)"},
+			"eu-west-1",
+		},
+		{
+			"override file",
+			"testdata/configs/override",
+			[]string{"module.queue.aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+			"ap-south-1",
+		},
+		{
+			"module used twice",
+			"testdata/configs/shared",
+			[]string{
+				"module.first.aws_sqs_queue (registry.terraform.io/hashicorp/aws)",
+				"module.second.aws_sqs_queue (registry.terraform.io/hashicorp/aws)",
+			},
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			parser := NewConfigParser(c.path)
+			resources, err := parser.GetResources(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, resource := range resources {
+				got = append(got, resource.String())
+			}
+			if !reflect.DeepEqual(got, c.resources) {
+				t.Errorf("got resources %v, want %v", got, c.resources)
+			}
+			values, err := parser.GetPlanValues(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values.Region != c.region {
+				t.Errorf("got region %q, want %q", values.Region, c.region)
+			}
+		})
+	}
+}
+
+func TestConfigParserValuesOfAliasedProvider(t *testing.T) {
+	values, err := NewConfigParser("testdata/configs/aliased").GetPlanValues(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]string)
+	for _, resource := range values.Resources {
+		names[resource.Address] = resource.Values.Get("name").String()
+	}
+	if want := map[string]string{"aws_sqs_queue.west": "west", "aws_sqs_queue.east": "east"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestConfigParserStopsAtModuleCycle(t *testing.T) {
+	_, err := NewConfigParser("testdata/configs/cycle").GetResources(context.Background())
+	var planErr *PlanError
+	if !errors.As(err, &planErr) || planErr.Op != "follow module module.a.module.b.module.a" {
+		t.Errorf("got %v, want the cycle to be reported", err)
+	}
+}
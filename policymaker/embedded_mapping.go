@@ -0,0 +1,81 @@
+package policymaker
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+/*
+These are the mappings shipped with the binary, named <provider>_<version>.json, e.g.
+aws_5.31.0.json. They are generated with the generate-mapping command, see mappings/README.md.
+*/
+//go:embed mappings
+var embeddedMappingFiles embed.FS
+
+// embeddedMappings is the file system the embedded mappings are read from
+var embeddedMappings fs.FS = embeddedMappingFiles
+
+// EmbeddedMappingFileName returns the name of the embedded mapping of a provider version, e.g. aws_5.31.0.json
+func EmbeddedMappingFileName(provider string, version string) string {
+	return fmt.Sprintf("%s_%s.json", provider, strings.TrimPrefix(version, "v"))
+}
+
+// EmbeddedMappingVersions returns the provider versions there is an embedded mapping for, oldest first
+func EmbeddedMappingVersions(provider string) []string {
+	entries, err := fs.ReadDir(embeddedMappings, "mappings")
+	if err != nil {
+		return nil
+	}
+	var versions []string
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		i := strings.LastIndex(name, "_")
+		if i < 0 || name[:i] != provider {
+			continue
+		}
+		versions = append(versions, name[i+1:])
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+/*
+EmbeddedMapping returns the embedded mapping of exactly the given provider version. It returns nil
+if there is none, or if the version is not known, as the resources and the actions they need change
+between versions.
+*/
+func EmbeddedMapping(provider string, version string) (*Mapping, error) {
+	version = strings.TrimPrefix(version, "v")
+	if version == "" {
+		return nil, nil
+	}
+	found := false
+	for _, embeddedVersion := range EmbeddedMappingVersions(provider) {
+		if embeddedVersion == version {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	fileName := EmbeddedMappingFileName(provider, version)
+	dat, err := fs.ReadFile(embeddedMappings, path.Join("mappings", fileName))
+	if err != nil {
+		return nil, err
+	}
+	mapping, _, err := LoadMapping(dat, provider)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,239 @@
+package policymaker
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// helper function that replaces the embedded mappings with the given versions of testdata/mappings/aws.json
+func withEmbeddedMappings(t *testing.T, files ...string) {
+	dat, err := ioutil.ReadFile("testdata/mappings/aws.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mappings := fstest.MapFS{"mappings/README.md": {Data: []byte("not a mapping")}}
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "aws_"), ".json")
+		content := strings.Replace(string(dat), `"provider_version": ""`, `"provider_version": "`+version+`"`, 1)
+		mappings[path.Join("mappings", file)] = &fstest.MapFile{Data: []byte(content)}
+	}
+	previous := embeddedMappings
+	embeddedMappings = mappings
+	t.Cleanup(func() { embeddedMappings = previous })
+}
+
+func TestEmbeddedMappingVersion(t *testing.T) {
+	withEmbeddedMappings(t, "aws_5.30.0.json", "aws_5.31.0.json")
+	if got, want := EmbeddedMappingVersions(awsProvider), []string{"5.30.0", "5.31.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got versions %v, want %v", got, want)
+	}
+	cases := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{"exact version", "5.30.0", "5.30.0"},
+		{"tag of the version", "v5.31.0", "5.31.0"},
+		{"other minor version", "5.32.0", ""},
+		{"other major version", "4.67.0", ""},
+		{"partial version", "5.31", ""},
+		{"pre-release", "5.31.0-beta1", ""},
+		{"unknown version", "", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mapping, err := EmbeddedMapping(awsProvider, c.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if mapping != nil {
+				got = mapping.ProviderVersion
+			}
+			if got != c.want {
+				t.Errorf("got the mapping of %q, want %q", got, c.want)
+			}
+		})
+	}
+	if mapping, err := EmbeddedMapping(googleProvider, "5.31.0"); mapping != nil || err != nil {
+		t.Errorf("got %v, %v for a provider without embedded mappings", mapping, err)
+	}
+}
+
+func TestEmbeddedMappingsAreGenerated(t *testing.T) {
+	entries, err := fs.ReadDir(embeddedMappingFiles, "mappings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			name := strings.TrimSuffix(entry.Name(), ".json")
+			i := strings.LastIndex(name, "_")
+			if i < 0 {
+				t.Fatalf("%s is not named <provider>_<version>.json", entry.Name())
+			}
+			dat, err := fs.ReadFile(embeddedMappingFiles, path.Join("mappings", entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mapping, version, err := LoadMapping(dat, name[:i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != MappingSchemaVersion || mapping.ProviderVersion != name[i+1:] {
+				t.Errorf("got schema version %d for provider version %q", version, mapping.ProviderVersion)
+			}
+			if mapping.SourceCommit == "" || mapping.GeneratedAt == "" || mapping.GeneratorVersion == "" {
+				t.Error("the mapping does not record where it was generated from, write it with generate-mapping")
+			}
+		})
+	}
+}
+
+// helper function that copies the files under src into dst
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), content, 0644)
+	})
+}
+
+func TestGetPermissionsMapGeneratesResourcesMissingFromEmbeddedMapping(t *testing.T) {
+	source, err := filepath.Abs(awsFixtureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withEmbeddedMappings(t, "aws_1.0.0.json")
+	inTempDir(t)
+	providerParser := NewProviderParser("", awsProvider, true)
+	providerParser.SetVersion("1.0.0")
+	// the source has been downloaded before, so nothing is downloaded
+	if err := copyDir(source, providerParser.SourceDir); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := providerParser.GetPermissionsMap([]*Resource{NewResource("aws_sqs_queue", "managed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.SourceCommit != "" || mapping.Resources["resource_aws_s3_bucket"] != nil {
+		t.Error("the embedded mapping holds the resource, but was not used")
+	}
+
+	mapping, err = providerParser.GetPermissionsMap([]*Resource{NewResource("aws_sqs_queue", "managed"), NewResource("aws_s3_bucket", "managed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Resources["resource_aws_s3_bucket"] == nil {
+		t.Error("the resource missing from the embedded mapping was not generated from the source")
+	}
+	if !exists(providerParser.OutputFile) {
+		t.Error("the generated mapping was not cached")
+	}
+}
+
+/*
+helper function that replaces the embedded mappings with the mapping generate-mapping builds from the
+source of the fixture provider, as version 5.31.0
+*/
+func withGeneratedEmbeddedMapping(t *testing.T) {
+	source, err := filepath.Abs(awsFixtureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	providerParser := NewProviderParser("", awsProvider, true)
+	providerParser.SetVersion("5.31.0")
+	providerParser.Source = &LocalSource{Dir: source}
+	mapping, err := providerParser.GenerateMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := mapping.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := embeddedMappings
+	embeddedMappings = fstest.MapFS{path.Join("mappings", EmbeddedMappingFileName(awsProvider, "5.31.0")): {Data: content}}
+	t.Cleanup(func() { embeddedMappings = previous })
+}
+
+func TestGeneratePolicyDocumentFromEmbeddedMapping(t *testing.T) {
+	planFile, _ := filepath.Abs("testdata/plans/pinned_provider.json")
+	withGeneratedEmbeddedMapping(t)
+	inTempDir(t)
+
+	// offline, generating the mapping from the source fails instead of downloading it
+	pm := &PolicyMaker{
+		Provider:        awsProvider,
+		UseCache:        true,
+		Offline:         true,
+		ResourceParser:  NewPlanFileParser(planFile),
+		ProviderParsers: make(map[string]*ProviderParser),
+		ActionCatalogue: DefaultActionCatalogue(),
+	}
+	if err := pm.GeneratePolicyDocument(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dat, err := ioutil.ReadFile("aws_policy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"s3:CreateBucket", "s3:PutBucketTagging", "ec2:DescribeImages"} {
+		if !strings.Contains(string(dat), `"`+action+`"`) {
+			t.Errorf("the policy does not grant %s:\n%s", action, dat)
+		}
+	}
+	if files, _ := filepath.Glob("terraform-provider-aws*"); len(files) > 0 {
+		t.Errorf("expected the provider source not to be fetched, got %v", files)
+	}
+	if exists("aws_5.31.0_mapping.json") {
+		t.Error("the embedded mapping was cached in the working directory")
+	}
+}
+
+func TestGetPermissionsMapOfVersionWithoutEmbeddedMappingOffline(t *testing.T) {
+	withGeneratedEmbeddedMapping(t)
+	cases := []struct {
+		name    string
+		version string
+	}{
+		{"other version", "5.32.0"},
+		{"unknown version", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inTempDir(t)
+			providerParser := NewProviderParser("", awsProvider, true)
+			providerParser.SetVersion(c.version)
+			providerParser.Offline = true
+			_, err := providerParser.GetPermissionsMap([]*Resource{NewResource("aws_s3_bucket", "managed")})
+			if err == nil || !strings.Contains(err.Error(), "-offline") {
+				t.Errorf("expected an error about -offline, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package policymaker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxStderrLength is how much of the end of a command's stderr is included in a CommandError message
+const maxStderrLength = 4096
+
+// CommandError is returned when a command could not be run or exited unsuccessfully
+type CommandError struct {
+	Command  string
+	Dir      string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+func (e *CommandError) Error() string {
+	message := fmt.Sprintf("command %q failed: %s", e.Command, e.Err)
+	if e.ExitCode > 0 {
+		message = fmt.Sprintf("command %q exited with status %d", e.Command, e.ExitCode)
+	}
+	if e.Dir != "" {
+		message += fmt.Sprintf(" in %s", e.Dir)
+	}
+	stderr := strings.TrimSpace(e.Stderr)
+	if len(stderr) > maxStderrLength {
+		stderr = "..." + stderr[len(stderr)-maxStderrLength:]
+	}
+	if stderr != "" {
+		message += "\n" + stderr
+	}
+	return message
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderError is returned when the provider source code could not be downloaded or parsed
+type ProviderError struct {
+	Provider string
+	Op       string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("provider %s: %s: %s", e.Provider, e.Op, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// PlanError is returned when the resources of a configuration could not be determined
+type PlanError struct {
+	Path string
+	Op   string
+	Err  error
+}
+
+func (e *PlanError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Op, e.Path, e.Err)
+}
+
+func (e *PlanError) Unwrap() error {
+	return e.Err
+}
+
+// PolicyError is returned when a policy document could not be generated or written
+type PolicyError struct {
+	Op  string
+	Err error
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Op, e.Err)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
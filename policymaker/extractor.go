@@ -0,0 +1,57 @@
+package policymaker
+
+import (
+	"sync"
+)
+
+/*
+PermissionExtractor builds the mapping of a terraform provider from its source code, which has
+already been fetched to sourceDir. Extractors only fill in the resources of the mapping, its header
+is filled in by ProviderParser.
+*/
+type PermissionExtractor interface {
+	Extract(sourceDir string) (*Mapping, error)
+}
+
+/*
+RewritesReporter is implemented by extractors that rewrite the operations they find into
+permissions, so that the rewrites of the last Extract can be audited
+*/
+type RewritesReporter interface {
+	Rewrites() []ActionRewrite
+}
+
+// ExtractorFactory creates the extractor of a provider
+type ExtractorFactory func() PermissionExtractor
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = make(map[string]ExtractorFactory)
+)
+
+/*
+RegisterExtractor makes the extractor of a provider, e.g. aws, available to ProviderParser. Registering
+a provider again replaces its extractor, so that other modules can add or override providers.
+*/
+func RegisterExtractor(provider string, factory ExtractorFactory) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors[provider] = factory
+}
+
+// NewExtractor returns a new extractor for a provider, or nil if none is registered
+func NewExtractor(provider string) PermissionExtractor {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	if factory, ok := extractors[provider]; ok {
+		return factory()
+	}
+	return nil
+}
+
+func init() {
+	RegisterExtractor(awsProvider, func() PermissionExtractor { return NewAWSExtractor() })
+	RegisterExtractor(googleProvider, func() PermissionExtractor { return NewGCPExtractor() })
+	RegisterExtractor(googleBetaProvider, func() PermissionExtractor { return NewGCPExtractor() })
+	RegisterExtractor(azurermProvider, func() PermissionExtractor { return NewAzureExtractor() })
+}
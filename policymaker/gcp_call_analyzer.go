@@ -0,0 +1,458 @@
+package policymaker
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// gcpAPIPackagePrefix is the import path of the generated API clients
+const gcpAPIPackagePrefix = "google.golang.org/api/"
+
+var (
+	// basePathRegexp matches the base path variable a REST URL template starts with, e.g. {{ComputeBasePath}}
+	basePathRegexp = regexp.MustCompile(`\{\{(\w+)BasePath\}\}`)
+	// newClientRegexp matches the name of a function or field that returns a generated API client
+	newClientRegexp = regexp.MustCompile(`^(?:New(\w+)Client|client(\w+))$`)
+	// apiVersionRegexp matches the version suffix of an API name, e.g. the Beta in ComputeBeta
+	apiVersionRegexp = regexp.MustCompile(`(?:Beta|Alpha|V\d+(?:Beta\d*)?)$`)
+)
+
+/*
+gcpServiceNames maps the lowercase API names used by terraform-provider-google (in base paths
+like {{SQLBasePath}}, clients like NewSqlAdminClient and packages like google.golang.org/api/sqladmin)
+to their IAM service, where it is not simply the API name
+*/
+var gcpServiceNames = map[string]string{
+	"sql":                  "cloudsql",
+	"sqladmin":             "cloudsql",
+	"kms":                  "cloudkms",
+	"cloudrun":             "run",
+	"cloudresourcemanager": "resourcemanager",
+	"cloudbilling":         "billing",
+	"firestore":            "datastore",
+}
+
+// gcpCollectionNames maps the collections of APIs that abbreviate them in URLs to their IAM name
+var gcpCollectionNames = map[string]string{
+	"storage.b": "buckets",
+	"storage.o": "objects",
+}
+
+// gcpClientVerbs maps the methods of the generated API clients to the verbs of IAM permissions
+var gcpClientVerbs = map[string]string{
+	"Insert":         "create",
+	"Create":         "create",
+	"Get":            "get",
+	"List":           "list",
+	"AggregatedList": "list",
+	"Patch":          "update",
+	"Update":         "update",
+	"Delete":         "delete",
+}
+
+/*
+gcpCustomVerbs are the first words of the custom methods in REST URLs, e.g. setMetadata in
+instances/{{name}}/setMetadata, which tell them apart from collections like backendServices
+*/
+var gcpCustomVerbs = map[string]bool{
+	"abandon": true, "add": true, "attach": true, "clone": true, "create": true, "delete": true,
+	"deprecate": true, "detach": true, "disable": true, "enable": true, "export": true, "failover": true,
+	"get": true, "import": true, "invalidate": true, "move": true, "patch": true, "promote": true,
+	"recreate": true, "remove": true, "reset": true, "resize": true, "restart": true, "restore": true,
+	"resume": true, "rollback": true, "set": true, "start": true, "stop": true, "suspend": true,
+	"undelete": true, "update": true, "validate": true,
+}
+
+/*
+gcpCallAnalyzer finds the IAM permissions (e.g. compute.instances.create) needed by the REST
+requests made with sendRequest, whose URLs are built from templates like
+{{ComputeBasePath}}projects/{{project}}/global/networks, and by calls made on the generated
+API clients, e.g. config.NewComputeClient(userAgent).Instances.Insert(...)
+*/
+type gcpCallAnalyzer struct {
+	*callGraph
+	// params maps a function parameter declared as a generated client, e.g. *compute.Service, to its IAM service
+	params map[types.Object]string
+}
+
+// newGCPCallAnalyzer type checks the package in a directory and collects its requests
+func newGCPCallAnalyzer(dir string) (*gcpCallAnalyzer, error) {
+	g, err := newCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	a := &gcpCallAnalyzer{
+		callGraph: g,
+		params:    make(map[types.Object]string),
+	}
+	g.findOperations = a.findOperations
+	g.operationPhases = gcpPermissionPhases
+	a.resolveTypedParams()
+	a.collectCalls()
+	return a, nil
+}
+
+/*
+resolveTypedParams finds the parameters declared as a generated client, which is the Service type
+of a package under google.golang.org/api, e.g. func setMetadata(client *compute.Service)
+*/
+func (a *gcpCallAnalyzer) resolveTypedParams() {
+	for _, file := range a.files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var ft *ast.FuncType
+			switch f := n.(type) {
+			case *ast.FuncDecl:
+				ft = f.Type
+			case *ast.FuncLit:
+				ft = f.Type
+			default:
+				return true
+			}
+			for _, field := range ft.Params.List {
+				service := a.clientTypeService(field.Type)
+				if service == "" {
+					continue
+				}
+				for _, name := range field.Names {
+					if obj := a.objectOf(name); obj != nil {
+						a.params[obj] = service
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// helper function that returns the IAM service of a type expression like *compute.Service
+func (a *gcpCallAnalyzer) clientTypeService(expr ast.Expr) string {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Service" {
+		return ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	pkgName, ok := a.info.Uses[ident].(*types.PkgName)
+	if !ok {
+		return ""
+	}
+	// e.g. google.golang.org/api/compute/v1
+	parts := strings.Split(strings.TrimPrefix(pkgName.Imported().Path(), gcpAPIPackagePrefix), "/")
+	if !strings.HasPrefix(pkgName.Imported().Path(), gcpAPIPackagePrefix) || parts[0] == "" {
+		return ""
+	}
+	return gcpServiceName(parts[0])
+}
+
+/*
+findOperations records the permissions needed within a node. URL templates and clients are
+tracked per variable in source order, so a request uses the URL most recently assigned.
+*/
+func (a *gcpCallAnalyzer) findOperations(node ast.Node, operations map[string]token.Pos) {
+	urls := make(map[string]string)
+	clients := make(map[string]string)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range e.Lhs {
+				var rhs ast.Expr
+				switch {
+				case len(e.Rhs) == len(e.Lhs):
+					rhs = e.Rhs[i]
+				case i == 0:
+					// e.g. url, err := replaceVars(...) assigns the template to the first variable
+					rhs = e.Rhs[0]
+				default:
+					continue
+				}
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if template := urlTemplate(rhs, urls); template != "" {
+					urls[ident.Name] = template
+				}
+				if service := a.clientService(rhs, clients); service != "" {
+					clients[ident.Name] = service
+				}
+			}
+		case *ast.CallExpr:
+			if permission := requestPermission(e, urls); permission != "" {
+				addOperation(operations, permission, e.Pos())
+			}
+			if permission := a.clientPermission(e, clients); permission != "" {
+				addOperation(operations, permission, e.Pos())
+			}
+		}
+		return true
+	})
+}
+
+// helper function that returns the URL template an expression evaluates to, if any
+func urlTemplate(expr ast.Expr, urls map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return urlTemplate(e.X, urls)
+	case *ast.Ident:
+		return urls[e.Name]
+	case *ast.BasicLit:
+		if value, err := strconv.Unquote(e.Value); err == nil && basePathRegexp.MatchString(value) {
+			return value
+		}
+	case *ast.BinaryExpr:
+		// e.g. url + "?updateMask=labels"
+		if e.Op == token.ADD {
+			return urlTemplate(e.X, urls)
+		}
+	case *ast.CallExpr:
+		// replaceVars(d, config, "{{ComputeBasePath}}...") or AddQueryParams(url, ...)
+		for _, arg := range e.Args {
+			if template := urlTemplate(arg, urls); template != "" {
+				return template
+			}
+		}
+	}
+	return ""
+}
+
+/*
+requestPermission returns the permission a call to sendRequest (or one of its variants) needs,
+given the HTTP method and URL it is called with
+*/
+func requestPermission(call *ast.CallExpr, urls map[string]string) string {
+	if !strings.HasPrefix(strings.ToLower(funcName(call.Fun)), "sendrequest") {
+		return ""
+	}
+	var method, template string
+	args := call.Args
+	// newer versions take a SendRequestOptions struct instead of positional arguments
+	if len(args) == 1 {
+		if lit, ok := args[0].(*ast.CompositeLit); ok {
+			args = nil
+			for _, elt := range lit.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					args = append(args, kv.Value)
+				}
+			}
+		}
+	}
+	for _, arg := range args {
+		if lit, ok := arg.(*ast.BasicLit); ok && method == "" {
+			if value, err := strconv.Unquote(lit.Value); err == nil && isHTTPMethod(value) {
+				method = value
+				continue
+			}
+		}
+		if template == "" {
+			template = urlTemplate(arg, urls)
+		}
+	}
+	if method == "" || template == "" {
+		return ""
+	}
+	return restPermission(method, template)
+}
+
+/*
+restPermission derives a permission from an HTTP method and a URL template: the service from
+the base path, the collection from the last literal path segment and the verb from the method,
+or the custom method at the end of the URL
+*/
+func restPermission(method string, template string) string {
+	matches := basePathRegexp.FindStringSubmatch(template)
+	if matches == nil {
+		return ""
+	}
+	service := gcpServiceName(matches[1])
+	path := strings.SplitN(basePathRegexp.ReplaceAllString(template, ""), "?", 2)[0]
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	var verb string
+	last := segments[len(segments)-1]
+	if i := strings.LastIndex(last, ":"); i >= 0 {
+		// e.g. topics/{{name}}:setIamPolicy
+		verb = last[i+1:]
+		segments[len(segments)-1] = last[:i]
+	} else if last == "iam" && len(segments) > 1 {
+		// e.g. the storage API gets and sets the policy of a bucket at b/{{bucket}}/iam
+		verb = "setIamPolicy"
+		if strings.ToUpper(method) == "GET" {
+			verb = "getIamPolicy"
+		}
+		segments = segments[:len(segments)-1]
+	} else if !isURLVariable(last) && len(segments) > 1 && isCustomVerb(last) {
+		// e.g. instances/{{name}}/setMetadata
+		verb = last
+		segments = segments[:len(segments)-1]
+	}
+	item := isURLVariable(segments[len(segments)-1])
+	collection := ""
+	for i := len(segments) - 1; i >= 0 && collection == ""; i-- {
+		if !isURLVariable(segments[i]) {
+			collection = segments[i]
+		}
+	}
+	if collection == "" {
+		return ""
+	}
+	if name, ok := gcpCollectionNames[service+"."+collection]; ok {
+		collection = name
+	}
+	if verb == "" {
+		switch strings.ToUpper(method) {
+		case "GET":
+			verb = "list"
+			if item {
+				verb = "get"
+			}
+		case "POST":
+			verb = "create"
+			if item {
+				verb = "update"
+			}
+		case "PUT", "PATCH":
+			verb = "update"
+		case "DELETE":
+			verb = "delete"
+		}
+	}
+	return service + "." + collection + "." + verb
+}
+
+/*
+clientPermission returns the permission a method call on a generated API client needs, e.g.
+client.Projects.Locations.Functions.Create(...) needs cloudfunctions.functions.create
+*/
+func (a *gcpCallAnalyzer) clientPermission(call *ast.CallExpr, clients map[string]string) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !ast.IsExported(sel.Sel.Name) {
+		return ""
+	}
+	var collections []string
+	expr := sel.X
+	for {
+		inner, ok := expr.(*ast.SelectorExpr)
+		if !ok || !ast.IsExported(inner.Sel.Name) || newClientRegexp.MatchString(inner.Sel.Name) {
+			break
+		}
+		collections = append(collections, inner.Sel.Name)
+		expr = inner.X
+	}
+	if len(collections) == 0 {
+		return ""
+	}
+	service := a.clientService(expr, clients)
+	if service == "" {
+		return ""
+	}
+	verb, ok := gcpClientVerbs[sel.Sel.Name]
+	if !ok {
+		verb = lowerFirst(sel.Sel.Name)
+	}
+	// the collection nearest to the method, e.g. Functions in Projects.Locations.Functions
+	return service + "." + lowerFirst(collections[0]) + "." + verb
+}
+
+// helper function that returns the IAM service of the client an expression evaluates to, if any
+func (a *gcpCallAnalyzer) clientService(expr ast.Expr, clients map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return a.clientService(e.X, clients)
+	case *ast.Ident:
+		if service := clients[e.Name]; service != "" {
+			return service
+		}
+		return a.params[a.objectOf(e)]
+	case *ast.SelectorExpr:
+		// e.g. config.clientCompute
+		if matches := newClientRegexp.FindStringSubmatch(e.Sel.Name); matches != nil && matches[2] != "" {
+			return gcpServiceName(matches[2])
+		}
+	case *ast.CallExpr:
+		// e.g. config.NewComputeClient(userAgent)
+		if matches := newClientRegexp.FindStringSubmatch(funcName(e.Fun)); matches != nil && matches[1] != "" {
+			return gcpServiceName(matches[1])
+		}
+	}
+	return ""
+}
+
+// helper function that turns an API name like ComputeBeta or SqlAdmin into its IAM service
+func gcpServiceName(api string) string {
+	api = strings.ToLower(apiVersionRegexp.ReplaceAllString(api, ""))
+	if service, ok := gcpServiceNames[api]; ok {
+		return service
+	}
+	return api
+}
+
+/*
+gcpPermissionPhases classifies a permission like compute.instances.create by its verb. Anything
+that is not a create, read or delete changes an existing resource.
+*/
+func gcpPermissionPhases(permission string) []Phase {
+	verb := permission[strings.LastIndex(permission, ".")+1:]
+	switch {
+	case verb == "create":
+		return []Phase{PhaseCreate}
+	case verb == "delete":
+		return []Phase{PhaseDelete}
+	case strings.HasPrefix(verb, "get") || strings.HasPrefix(verb, "list"):
+		return []Phase{PhaseRead}
+	}
+	return []Phase{PhaseUpdate}
+}
+
+// helper function that returns the name of the function being called
+func funcName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		return f.Sel.Name
+	}
+	return ""
+}
+
+func isHTTPMethod(value string) bool {
+	switch value {
+	case "GET", "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+func isURLVariable(segment string) bool {
+	return strings.HasPrefix(segment, "{{") && strings.HasSuffix(segment, "}}")
+}
+
+// helper function that tells whether a path segment is a custom method, by its first word
+func isCustomVerb(segment string) bool {
+	word := strings.FieldsFunc(segment, unicode.IsUpper)
+	return len(word) > 0 && strings.HasPrefix(segment, word[0]) && gcpCustomVerbs[word[0]]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,78 @@
+package policymaker
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const gcpFixtureSource = "testdata/providers/terraform-provider-google"
+
+func TestGCPCallAnalyzer(t *testing.T) {
+	analyzer, err := newGCPCallAnalyzer(filepath.Join(gcpFixtureSource, "google"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	operationsByFile := analyzer.OperationsByFile(isTerraformResourceFile)
+	got := make(map[string]map[Phase][]string)
+	for path, operationsByPhase := range operationsByFile {
+		got[filepath.Base(path)] = make(map[Phase][]string)
+		for phase, operations := range operationsByPhase {
+			for _, operation := range operations {
+				got[filepath.Base(path)][phase] = append(got[filepath.Base(path)][phase], fmt.Sprintf("%s:%d", operation.Name, operation.Position.Line))
+			}
+			sort.Strings(got[filepath.Base(path)][phase])
+		}
+	}
+
+	cases := []struct {
+		name  string
+		file  string
+		phase Phase
+		want  []string
+	}{
+		{"client returned by a constructor", "resource_compute_instance.go", PhaseCreate, []string{"compute.instances.create:19", "compute.instances.get:25"}},
+		{"client field of the config", "resource_compute_instance.go", PhaseRead, []string{"compute.instances.get:25"}},
+		{"client passed into a helper", "resource_compute_instance.go", PhaseUpdate, []string{"compute.instances.setMetadata:37"}},
+		{"client method named after the verb", "resource_compute_instance.go", PhaseDelete, []string{"compute.instances.delete:42"}},
+		{"request to a collection", "resource_compute_network.go", PhaseCreate, []string{"compute.networks.create:20", "compute.networks.get:28"}},
+		{"custom method, query parameters and the read it returns", "resource_compute_network.go", PhaseUpdate, []string{"compute.networks.addPeering:37", "compute.networks.get:28", "compute.networks.update:35"}},
+		{"request options and abbreviated collection", "resource_storage_bucket.go", PhaseCreate, []string{"storage.buckets.create:19", "storage.buckets.setIamPolicy:25"}},
+		{"request options delete", "resource_storage_bucket.go", PhaseDelete, []string{"storage.buckets.delete:37"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if operations := got[c.file][c.phase]; !reflect.DeepEqual(operations, c.want) {
+				t.Errorf("%s %s: got %v, want %v", c.file, c.phase, operations, c.want)
+			}
+		})
+	}
+	if _, ok := got["config.go"]; ok {
+		t.Error("config.go is not a resource file")
+	}
+}
+
+func TestRestPermission(t *testing.T) {
+	cases := []struct {
+		method   string
+		template string
+		want     string
+	}{
+		{"GET", "{{ComputeBasePath}}projects/{{project}}/global/networks", "compute.networks.list"},
+		{"POST", "{{ComputeBasePath}}projects/{{project}}/zones/{{zone}}/instances/{{name}}/setMetadata", "compute.instances.setMetadata"},
+		{"POST", "{{PubsubBasePath}}projects/{{project}}/topics/{{name}}:setIamPolicy", "pubsub.topics.setIamPolicy"},
+		{"GET", "{{StorageBasePath}}b/{{bucket}}/iam", "storage.buckets.getIamPolicy"},
+		{"PATCH", "{{SQLBasePath}}projects/{{project}}/instances/{{name}}", "cloudsql.instances.update"},
+		{"GET", "{{ComputeBetaBasePath}}projects/{{project}}/global/backendServices/{{name}}", "compute.backendServices.get"},
+		{"GET", "https://example.com/{{name}}", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.method+" "+c.template, func(t *testing.T) {
+			if got := restPermission(c.method, c.template); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
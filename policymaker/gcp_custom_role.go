@@ -0,0 +1,63 @@
+package policymaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CustomRolePermissionsLimit is the maximum number of permissions a GCP custom role can include
+const CustomRolePermissionsLimit = 3000
+
+// CustomRoleStage is the launch stage of a generated custom role
+const CustomRoleStage = "GA"
+
+/*
+CustomRole is a GCP IAM custom role definition, in the format gcloud iam roles create --file
+and the IAM API accept
+*/
+type CustomRole struct {
+	Title               string   `json:"title"`
+	Description         string   `json:"description"`
+	Stage               string   `json:"stage"`
+	IncludedPermissions []string `json:"includedPermissions"`
+}
+
+// NewCustomRole is the Constructor for CustomRole
+func NewCustomRole(provider string) *CustomRole {
+	return &CustomRole{
+		Title:               fmt.Sprintf("Terraform %s", provider),
+		Description:         fmt.Sprintf("Permissions needed by terraform to manage the %s resources of a configuration, generated by terraform-policymaker", provider),
+		Stage:               CustomRoleStage,
+		IncludedPermissions: []string{},
+	}
+}
+
+// JSON returns the role definition as indented JSON
+func (r *CustomRole) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// YAML returns the role definition as YAML, e.g. for gcloud iam roles create --file
+func (r *CustomRole) YAML() []byte {
+	var b strings.Builder
+	// strings are written as JSON strings, which are valid double quoted YAML scalars
+	fmt.Fprintf(&b, "title: %s\n", quoteYAML(r.Title))
+	fmt.Fprintf(&b, "description: %s\n", quoteYAML(r.Description))
+	fmt.Fprintf(&b, "stage: %s\n", quoteYAML(r.Stage))
+	if len(r.IncludedPermissions) == 0 {
+		b.WriteString("includedPermissions: []\n")
+		return []byte(b.String())
+	}
+	b.WriteString("includedPermissions:\n")
+	for _, permission := range r.IncludedPermissions {
+		fmt.Fprintf(&b, "- %s\n", permission)
+	}
+	return []byte(b.String())
+}
+
+// helper function that quotes a string for YAML
+func quoteYAML(s string) string {
+	bytes, _ := json.Marshal(s)
+	return string(bytes)
+}
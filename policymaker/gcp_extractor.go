@@ -0,0 +1,44 @@
+package policymaker
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/*
+GCPExtractor builds the mapping of terraform-provider-google (or google-beta) from the REST requests
+and generated client calls its resources make, as IAM permissions like compute.instances.create
+*/
+type GCPExtractor struct{}
+
+// NewGCPExtractor is the Constructor for GCPExtractor
+func NewGCPExtractor() *GCPExtractor {
+	return &GCPExtractor{}
+}
+
+// Extract builds the mapping of the provider source in sourceDir
+func (e *GCPExtractor) Extract(sourceDir string) (*Mapping, error) {
+	extractor := &packageExtractor{
+		isResourceFile: isTerraformResourceFile,
+		resourceName:   gcpResourceName,
+		newAnalyzer: func(dir string) (callAnalyzer, error) {
+			return newGCPCallAnalyzer(dir)
+		},
+	}
+	return extractor.extract(sourceDir)
+}
+
+/*
+gcpResourceName returns the mapping key of a resource file. The files mostly leave out the provider,
+e.g. resource_compute_instance.go, so it is added to match the resource type google_compute_instance.
+Both google and google-beta name their resource types google_*.
+*/
+func gcpResourceName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".go")
+	for _, mode := range []string{"data_source", "resource"} {
+		if strings.HasPrefix(name, mode+"_") {
+			return resourceKey(mode, strings.TrimPrefix(name, mode+"_"), googleProvider)
+		}
+	}
+	return name
+}
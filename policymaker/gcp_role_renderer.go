@@ -0,0 +1,55 @@
+package policymaker
+
+import (
+	"fmt"
+)
+
+/*
+GCPRenderer renders the custom role of the resources of a google provider as <provider>_role.json
+and <provider>_role.yaml. GCP permissions cannot be scoped in the role itself, so every permission
+is simply included.
+*/
+type GCPRenderer struct {
+	Provider string
+}
+
+// NewGCPRenderer is the Constructor for GCPRenderer
+func NewGCPRenderer(provider string) *GCPRenderer {
+	return &GCPRenderer{Provider: provider}
+}
+
+// OutputPatterns returns the patterns of the custom role in both formats
+func (r *GCPRenderer) OutputPatterns() []string {
+	return []string{fmt.Sprintf("%s_role.json", r.Provider), fmt.Sprintf("%s_role.yaml", r.Provider)}
+}
+
+// Render returns the custom role both as JSON and as YAML
+func (r *GCPRenderer) Render(actions []*ResourceActions, values *PlanValues) ([]*Document, error) {
+	role := r.BuildCustomRole(actions)
+	content, err := role.JSON()
+	if err != nil {
+		return nil, &PolicyError{Op: "marshal custom role", Err: err}
+	}
+	summary := fmt.Sprintf("%d permissions", len(role.IncludedPermissions))
+	if len(role.IncludedPermissions) > CustomRolePermissionsLimit {
+		fmt.Printf("######### Warning: %d permissions exceed the limit of %d permissions in a custom role\n", len(role.IncludedPermissions), CustomRolePermissionsLimit)
+	}
+	return []*Document{
+		{FileName: fmt.Sprintf("%s_role.json", r.Provider), Content: content, Summary: summary},
+		{FileName: fmt.Sprintf("%s_role.yaml", r.Provider), Content: role.YAML(), Summary: summary},
+	}, nil
+}
+
+// BuildCustomRole builds a custom role including the permissions of every resource
+func (r *GCPRenderer) BuildCustomRole(actions []*ResourceActions) *CustomRole {
+	fmt.Printf("######### New Custom Role (%s)\n", r.Provider)
+	permissionsSet := make(map[string]bool)
+	for _, resourceActions := range actions {
+		for _, permission := range resourceActions.Actions {
+			permissionsSet[permission] = true
+		}
+	}
+	role := NewCustomRole(r.Provider)
+	role.IncludedPermissions = append(role.IncludedPermissions, sortedKeys(permissionsSet)...)
+	return role
+}
@@ -0,0 +1,299 @@
+package policymaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*
+MappingSchemaVersion is the version of the mapping file format written by this version. Version 1
+is a header-less object holding the actions of each resource per phase, and version 0 a
+header-less object holding a flat list of actions per resource. Both are migrated when loaded.
+*/
+const MappingSchemaVersion = 2
+
+/*
+Mapping maps the resources of a terraform provider to the actions they need in each phase of their
+lifecycle, along with where the mapping was generated from. It is stored as JSON:
+
+	{
+	  "schema_version": 2,
+	  "provider": "aws",
+	  "provider_version": "5.31.0",
+	  "source_commit": "<git SHA of the provider source, if it is a git checkout>",
+	  "generator_version": "<version of terraform-policymaker>",
+	  "generated_at": "2024-06-01T12:00:00Z",
+	  "resources": {
+	    "resource_aws_s3_bucket": {
+	      "create": [{"action": "s3:CreateBucket", "file": "aws/resource_aws_s3_bucket.go", "line": 123}],
+	      "read": [...],
+	      "update": [...],
+	      "delete": [...]
+	    }
+	  }
+	}
+
+Resources are keyed by their mode and type, i.e. resource_<type> or data_source_<type>. The file and
+line of an action are where the call it was found from is made, relative to the provider source.
+*/
+type Mapping struct {
+	SchemaVersion    int                         `json:"schema_version"`
+	Provider         string                      `json:"provider"`
+	ProviderVersion  string                      `json:"provider_version"`
+	SourceCommit     string                      `json:"source_commit"`
+	GeneratorVersion string                      `json:"generator_version"`
+	GeneratedAt      string                      `json:"generated_at"`
+	Resources        map[string]*ResourceMapping `json:"resources"`
+}
+
+// ResourceMapping holds the actions a resource needs during each lifecycle phase
+type ResourceMapping struct {
+	Create []*MappedAction `json:"create"`
+	Read   []*MappedAction `json:"read"`
+	Update []*MappedAction `json:"update"`
+	Delete []*MappedAction `json:"delete"`
+}
+
+// MappedAction is an action along with the place in the provider source it was found, if known
+type MappedAction struct {
+	Action string `json:"action"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+}
+
+// NewMapping is the Constructor for Mapping
+func NewMapping() *Mapping {
+	return &Mapping{
+		SchemaVersion: MappingSchemaVersion,
+		Resources:     make(map[string]*ResourceMapping),
+	}
+}
+
+// MappingKey returns the key of a resource in a mapping, e.g. resource_aws_instance or data_source_aws_ami
+func MappingKey(resource *Resource) string {
+	if resource.Mode == ModeData {
+		return "data_source_" + resource.Type
+	}
+	return "resource_" + resource.Type
+}
+
+// Lookup returns the actions a resource needs, or nil if the mapping does not hold the resource
+func (m *Mapping) Lookup(resource *Resource) *PhasePermissions {
+	resourceMapping := m.Resources[MappingKey(resource)]
+	if resourceMapping == nil {
+		return nil
+	}
+	return resourceMapping.Permissions()
+}
+
+// MissingKeys returns the sorted mapping keys of the resources the mapping does not hold
+func (m *Mapping) MissingKeys(resources []*Resource) []string {
+	missingSet := make(map[string]bool)
+	for _, resource := range resources {
+		if _, ok := m.Resources[MappingKey(resource)]; !ok {
+			missingSet[MappingKey(resource)] = true
+		}
+	}
+	return sortedKeys(missingSet)
+}
+
+// Get returns the actions needed during a phase
+func (r *ResourceMapping) Get(phase Phase) []*MappedAction {
+	switch phase {
+	case PhaseCreate:
+		return r.Create
+	case PhaseRead:
+		return r.Read
+	case PhaseUpdate:
+		return r.Update
+	case PhaseDelete:
+		return r.Delete
+	}
+	return nil
+}
+
+// Set replaces the actions needed during a phase
+func (r *ResourceMapping) Set(phase Phase, actions []*MappedAction) {
+	switch phase {
+	case PhaseCreate:
+		r.Create = actions
+	case PhaseRead:
+		r.Read = actions
+	case PhaseUpdate:
+		r.Update = actions
+	case PhaseDelete:
+		r.Delete = actions
+	}
+}
+
+// Permissions returns the actions of each phase without where they were found
+func (r *ResourceMapping) Permissions() *PhasePermissions {
+	permissions := &PhasePermissions{}
+	for _, phase := range AllPhases {
+		var actions []string
+		for _, action := range r.Get(phase) {
+			actions = append(actions, action.Action)
+		}
+		permissions.Set(phase, actions)
+	}
+	return permissions
+}
+
+// JSON returns the mapping as indented JSON
+func (m *Mapping) JSON() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+/*
+Validate checks that a mapping is complete and well formed: a supported schema version, a provider,
+resources keyed by their mode and type, and actions that are not empty and have a relative source file
+*/
+func (m *Mapping) Validate() error {
+	if m.SchemaVersion != MappingSchemaVersion {
+		return fmt.Errorf("unsupported schema version %d, expected %d", m.SchemaVersion, MappingSchemaVersion)
+	}
+	if m.Provider == "" {
+		return errors.New("missing provider")
+	}
+	if m.GeneratedAt != "" {
+		if _, err := time.Parse(time.RFC3339, m.GeneratedAt); err != nil {
+			return fmt.Errorf("invalid generated_at: %s", err)
+		}
+	}
+	if m.Resources == nil {
+		return errors.New("missing resources")
+	}
+	keys := make([]string, 0, len(m.Resources))
+	for key := range m.Resources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "resource_") && !strings.HasPrefix(key, "data_source_") {
+			return fmt.Errorf("resource %s: key must start with resource_ or data_source_", key)
+		}
+		resourceMapping := m.Resources[key]
+		if resourceMapping == nil {
+			return fmt.Errorf("resource %s: missing actions", key)
+		}
+		for _, phase := range AllPhases {
+			for i, action := range resourceMapping.Get(phase) {
+				if err := action.validate(); err != nil {
+					return fmt.Errorf("resource %s: %s action %d: %s", key, phase, i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// helper function that validates a single action
+func (a *MappedAction) validate() error {
+	if a == nil {
+		return errors.New("missing action")
+	}
+	if a.Action == "" {
+		return errors.New("empty action")
+	}
+	if strings.IndexFunc(a.Action, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("action %q contains whitespace", a.Action)
+	}
+	if a.Line < 0 {
+		return fmt.Errorf("action %s: negative line %d", a.Action, a.Line)
+	}
+	if a.File == "" && a.Line != 0 {
+		return fmt.Errorf("action %s: line without a file", a.Action)
+	}
+	if filepath.IsAbs(a.File) {
+		return fmt.Errorf("action %s: file %s is not relative to the provider source", a.Action, a.File)
+	}
+	return nil
+}
+
+/*
+LoadMapping parses and validates the mapping file of a provider. Files of an older schema version
+are migrated, in which case the returned version is the one the file was written with. Unknown
+fields, malformed values, files written by a newer version and files of another provider are rejected.
+*/
+func LoadMapping(data []byte, provider string) (*Mapping, int, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, 0, fmt.Errorf("not a mapping: %s", err)
+	}
+	if fields == nil {
+		return nil, 0, errors.New("not a mapping: null")
+	}
+	mapping := &Mapping{}
+	version := MappingSchemaVersion
+	if _, ok := fields["schema_version"]; ok {
+		if err := decodeStrict(data, mapping); err != nil {
+			return nil, 0, err
+		}
+		if mapping.SchemaVersion > MappingSchemaVersion {
+			return nil, 0, fmt.Errorf("schema version %d was written by a newer version, this version supports up to %d", mapping.SchemaVersion, MappingSchemaVersion)
+		}
+		if mapping.Provider != provider {
+			return nil, 0, fmt.Errorf("the mapping is for provider %s, not %s", mapping.Provider, provider)
+		}
+	} else {
+		var err error
+		if mapping, version, err = migrateMapping(fields); err != nil {
+			return nil, 0, err
+		}
+		mapping.Provider = provider
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, 0, err
+	}
+	return mapping, version, nil
+}
+
+/*
+helper function that migrates a header-less mapping, which holds either a flat list of actions per
+resource (version 0) or the actions per phase (version 1). The flat lists are classified by the
+verb of each action. Where the actions were found is not known.
+*/
+func migrateMapping(fields map[string]json.RawMessage) (*Mapping, int, error) {
+	mapping := NewMapping()
+	version := 1
+	for key, value := range fields {
+		permissions := &PhasePermissions{}
+		var actions []string
+		if err := json.Unmarshal(value, &actions); err == nil {
+			permissions = NewPhasePermissions(actions)
+			version = 0
+		} else if err := decodeStrict(value, permissions); err != nil {
+			return nil, 0, fmt.Errorf("resource %s: %s", key, err)
+		}
+		resourceMapping := &ResourceMapping{}
+		for _, phase := range AllPhases {
+			var mappedActions []*MappedAction
+			for _, action := range permissions.Get(phase) {
+				mappedActions = append(mappedActions, &MappedAction{Action: action})
+			}
+			resourceMapping.Set(phase, mappedActions)
+		}
+		mapping.Resources[key] = resourceMapping
+	}
+	return mapping, version, nil
+}
+
+// helper function that decodes JSON into v, rejecting unknown fields and trailing data
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after the mapping")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package policymaker
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMappingMigratesOlderVersions(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		version int
+		want    *PhasePermissions
+	}{
+		{
+			"version 0 holds a flat list of actions",
+			`{"resource_aws_sqs_queue": ["sqs:CreateQueue", "sqs:GetQueueAttributes", "sqs:SetQueueAttributes", "sqs:DeleteQueue"]}`,
+			0,
+			// the flat list is classified by verb, and Set actions are needed to create a resource as well
+			&PhasePermissions{
+				Create: []string{"sqs:CreateQueue", "sqs:SetQueueAttributes"},
+				Read:   []string{"sqs:GetQueueAttributes"},
+				Update: []string{"sqs:SetQueueAttributes"},
+				Delete: []string{"sqs:DeleteQueue"},
+			},
+		},
+		{
+			"version 1 holds the actions per phase",
+			`{"resource_aws_sqs_queue": {"create": ["sqs:CreateQueue"], "read": ["sqs:GetQueueAttributes"], "update": ["sqs:SetQueueAttributes"], "delete": ["sqs:DeleteQueue"]}}`,
+			1,
+			&PhasePermissions{
+				Create: []string{"sqs:CreateQueue"},
+				Read:   []string{"sqs:GetQueueAttributes"},
+				Update: []string{"sqs:SetQueueAttributes"},
+				Delete: []string{"sqs:DeleteQueue"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mapping, version, err := LoadMapping([]byte(c.data), awsProvider)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != c.version {
+				t.Errorf("got version %d, want %d", version, c.version)
+			}
+			if mapping.Provider != awsProvider || mapping.SchemaVersion != MappingSchemaVersion {
+				t.Errorf("got provider %q and schema version %d", mapping.Provider, mapping.SchemaVersion)
+			}
+			if got := mapping.Lookup(NewResource("aws_sqs_queue", "managed")); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestLoadMappingCurrentVersion(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/mappings/aws.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, version, err := LoadMapping(data, awsProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != MappingSchemaVersion || len(mapping.Resources) != 2 {
+		t.Errorf("got version %d with %d resources", version, len(mapping.Resources))
+	}
+}
+
+func TestLoadMappingRejects(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"newer schema version", `{"schema_version": 3, "provider": "aws", "resources": {}}`, "newer version"},
+		{"another provider", `{"schema_version": 2, "provider": "google", "resources": {}}`, "provider google"},
+		{"unknown field", `{"schema_version": 2, "provider": "aws", "resources": {}, "extra": true}`, "unknown field"},
+		{"not an object", `null`, "not a mapping"},
+		{"malformed resource", `{"resource_aws_sqs_queue": 1}`, "resource_aws_sqs_queue"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := LoadMapping([]byte(c.data), awsProvider)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("got %q, want it to mention %q", err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package policymaker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+callAnalyzer finds the operations each resource file of a provider makes, or each function
+returning a resource, per lifecycle phase
+*/
+type callAnalyzer interface {
+	OperationsByFile(filter func(string) bool) map[string]map[Phase][]operationCall
+	OperationsByFunc(names []string) map[string]map[Phase][]operationCall
+}
+
+// resourceRegistration is a resource or data source as the provider registers it
+type resourceRegistration struct {
+	// Key is the mapping key, e.g. resource_aws_s3_bucket
+	Key string
+	// Dir is the directory of the package declaring Factory
+	Dir string
+	// Factory is the name of the function that returns the resource
+	Factory string
+}
+
+/*
+packageExtractor is the common part of the extractors that analyze the Go source of a provider
+one package at a time. It finds the resource files, runs a call analyzer on every package that
+holds some, and collects the operations of each file into the mapping, along with where they are
+invoked.
+*/
+type packageExtractor struct {
+	// isResourceFile tells whether a file declares a terraform resource or data source
+	isResourceFile func(fileName string) bool
+	// resourceName returns the mapping key of a resource file
+	resourceName func(path string) string
+	// newAnalyzer returns the call analyzer of the package in a directory
+	newAnalyzer func(dir string) (callAnalyzer, error)
+	// translate turns an operation into permissions, or is nil if operations are permissions already
+	translate func(operation string) []string
+	// registrations lists the resources the provider registers, which are used instead of the resource
+	// files when there are any, or is nil if the resources are only known by their file names
+	registrations func(sourceDir string) ([]*resourceRegistration, error)
+}
+
+// extract builds the mapping of the provider source in sourceDir
+func (e *packageExtractor) extract(sourceDir string) (*Mapping, error) {
+	if e.registrations != nil {
+		registrations, err := e.registrations(sourceDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(registrations) > 0 {
+			return e.extractRegistered(sourceDir, registrations)
+		}
+	}
+	paths, err := getAllResourceFiles(sourceDir, e.isResourceFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no resource files found in %s", sourceDir)
+	}
+
+	// resource files are analyzed together with the rest of their package, so group them by directory
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		dirs[filepath.Dir(path)] = true
+	}
+	mapping := NewMapping()
+	for dir := range dirs {
+		analyzer, err := e.newAnalyzer(dir)
+		if err != nil {
+			return nil, err
+		}
+		operationsByFile := analyzer.OperationsByFile(e.isResourceFile)
+		for path, operationsByPhase := range operationsByFile {
+			resourceMapping := &ResourceMapping{}
+			for _, phase := range AllPhases {
+				resourceMapping.Set(phase, e.mappedActions(sourceDir, operationsByPhase[phase]))
+			}
+			mapping.Resources[e.resourceName(path)] = resourceMapping
+		}
+	}
+	return mapping, nil
+}
+
+/*
+extractRegistered builds the mapping from the functions the resources are registered with, analyzing
+each package that declares some. Every registered resource is in the mapping, even if it needs no
+actions.
+*/
+func (e *packageExtractor) extractRegistered(sourceDir string, registrations []*resourceRegistration) (*Mapping, error) {
+	registrationsByDir := make(map[string][]*resourceRegistration)
+	for _, registration := range registrations {
+		registrationsByDir[registration.Dir] = append(registrationsByDir[registration.Dir], registration)
+	}
+	mapping := NewMapping()
+	for dir, dirRegistrations := range registrationsByDir {
+		analyzer, err := e.newAnalyzer(dir)
+		if err != nil {
+			return nil, err
+		}
+		factories := make([]string, len(dirRegistrations))
+		for i, registration := range dirRegistrations {
+			factories[i] = registration.Factory
+		}
+		operationsByFunc := analyzer.OperationsByFunc(factories)
+		for _, registration := range dirRegistrations {
+			resourceMapping := &ResourceMapping{}
+			for _, phase := range AllPhases {
+				resourceMapping.Set(phase, e.mappedActions(sourceDir, operationsByFunc[registration.Factory][phase]))
+			}
+			mapping.Resources[registration.Key] = resourceMapping
+		}
+	}
+	return mapping, nil
+}
+
+/*
+helper function that turns operations into a sorted list of unique actions. An action that more
+than one operation translates into is attributed to the first of them.
+*/
+func (e *packageExtractor) mappedActions(sourceDir string, operations []operationCall) []*MappedAction {
+	actionsMap := make(map[string]*MappedAction)
+	for _, operation := range operations {
+		actions := []string{operation.Name}
+		if e.translate != nil {
+			actions = e.translate(operation.Name)
+		}
+		for _, action := range actions {
+			if actionsMap[action] != nil {
+				continue
+			}
+			mappedAction := &MappedAction{Action: action}
+			if file, err := filepath.Rel(sourceDir, operation.Position.Filename); err == nil && operation.Position.IsValid() {
+				mappedAction.File = filepath.ToSlash(file)
+				mappedAction.Line = operation.Position.Line
+			}
+			actionsMap[action] = mappedAction
+		}
+	}
+	mappedActions := make([]*MappedAction, 0, len(actionsMap))
+	for _, mappedAction := range actionsMap {
+		mappedActions = append(mappedActions, mappedAction)
+	}
+	sort.Slice(mappedActions, func(i, j int) bool {
+		return mappedActions[i].Action < mappedActions[j].Action
+	})
+	return mappedActions
+}
+
+/*
+This method reads the provider source code from a local folder and returns a list of all
+terraform resource and terrafrom data source files
+*/
+func getAllResourceFiles(dir string, isResourceFile func(fileName string) bool) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		isVendorFile := strings.Contains(filepath.Dir(path), "vendor")
+		if !isVendorFile && isResourceFile(file.Name()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// helper function for validating that a file is a terraform resource or data resource
+func isTerraformResourceFile(fileName string) bool {
+	//must be a go file
+	if !strings.HasSuffix(fileName, ".go") {
+		return false
+	}
+	//test files not allowed
+	if strings.HasSuffix(fileName, "test.go") {
+		return false
+	}
+
+	//and have a prefix that either either a data resource of resource
+	if strings.HasPrefix(fileName, "data_source") || strings.HasPrefix(fileName, "resource") {
+		return true
+	}
+	//otherwise it is invalid
+	return false
+}
+
+/*
+helper function that returns the mapping key of a resource or data source, adding the provider
+prefix to the type if the file name leaves it out, e.g. resource_google_compute_instance for
+resource_compute_instance.go
+*/
+func resourceKey(mode string, typeName string, typePrefix string) string {
+	if !strings.HasPrefix(typeName, typePrefix+"_") {
+		typeName = typePrefix + "_" + typeName
+	}
+	return mode + "_" + typeName
+}
@@ -0,0 +1,174 @@
+package policymaker
+
+import (
+	"sort"
+	"strings"
+)
+
+// Phase represents a stage in the lifecycle of a terraform resource
+type Phase string
+
+// List of available lifecycle phases.
+const (
+	PhaseCreate Phase = "create"
+	PhaseRead   Phase = "read"
+	PhaseUpdate Phase = "update"
+	PhaseDelete Phase = "delete"
+)
+
+// AllPhases lists every lifecycle phase in the order terraform goes through them
+var AllPhases = []Phase{PhaseCreate, PhaseRead, PhaseUpdate, PhaseDelete}
+
+/*
+This maps the verb an IAM action starts with to the lifecycle phases it is usually called in.
+Verbs that are not listed are assumed to be needed for every phase that writes.
+*/
+var actionVerbPhases = map[string][]Phase{
+	"Describe":     {PhaseRead},
+	"Get":          {PhaseRead},
+	"List":         {PhaseRead},
+	"Head":         {PhaseRead},
+	"Search":       {PhaseRead},
+	"Lookup":       {PhaseRead},
+	"Create":       {PhaseCreate},
+	"Run":          {PhaseCreate},
+	"Allocate":     {PhaseCreate},
+	"Import":       {PhaseCreate},
+	"Register":     {PhaseCreate},
+	"Update":       {PhaseUpdate},
+	"Modify":       {PhaseUpdate},
+	"Change":       {PhaseCreate, PhaseUpdate, PhaseDelete},
+	"Reset":        {PhaseUpdate},
+	"Replace":      {PhaseUpdate},
+	"Put":          {PhaseCreate, PhaseUpdate},
+	"Set":          {PhaseCreate, PhaseUpdate},
+	"Tag":          {PhaseCreate, PhaseUpdate},
+	"Add":          {PhaseCreate, PhaseUpdate},
+	"Attach":       {PhaseCreate, PhaseUpdate},
+	"Associate":    {PhaseCreate, PhaseUpdate},
+	"Authorize":    {PhaseCreate, PhaseUpdate},
+	"Enable":       {PhaseCreate, PhaseUpdate},
+	"Start":        {PhaseCreate, PhaseUpdate},
+	"Untag":        {PhaseUpdate},
+	"Revoke":       {PhaseUpdate, PhaseDelete},
+	"Remove":       {PhaseUpdate, PhaseDelete},
+	"Detach":       {PhaseUpdate, PhaseDelete},
+	"Disassociate": {PhaseUpdate, PhaseDelete},
+	"Disable":      {PhaseUpdate, PhaseDelete},
+	"Stop":         {PhaseUpdate, PhaseDelete},
+	"Delete":       {PhaseDelete},
+	"Deregister":   {PhaseDelete},
+	"Terminate":    {PhaseDelete},
+	"Release":      {PhaseDelete},
+}
+
+/*
+actionPhases guesses the lifecycle phases an IAM action is needed for from the verb it starts
+with, e.g. s3:GetObject is a read and s3:PutBucketPolicy is needed to create or update.
+*/
+func actionPhases(action string) []Phase {
+	parts := strings.SplitN(action, ":", 2)
+	if len(parts) < 2 || strings.Contains(parts[1], "*") {
+		return AllPhases
+	}
+	name := parts[1]
+	verb := ""
+	for candidate := range actionVerbPhases {
+		if strings.HasPrefix(name, candidate) && len(candidate) > len(verb) {
+			verb = candidate
+		}
+	}
+	if verb == "" {
+		return []Phase{PhaseCreate, PhaseUpdate, PhaseDelete}
+	}
+	return actionVerbPhases[verb]
+}
+
+/*
+changePhases converts the change actions terraform plans for a resource instance (create,
+update, delete, no-op, read) into the lifecycle phases that need permissions. Terraform reads
+every resource back after writing it, so every change needs read permissions as well.
+*/
+func changePhases(changeActions []string) []Phase {
+	phases := []Phase{PhaseRead}
+	for _, changeAction := range changeActions {
+		switch changeAction {
+		case "create":
+			phases = append(phases, PhaseCreate)
+		case "update":
+			phases = append(phases, PhaseUpdate)
+		case "delete":
+			phases = append(phases, PhaseDelete)
+		}
+	}
+	return phases
+}
+
+// PhasePermissions holds the IAM actions a resource needs during each lifecycle phase
+type PhasePermissions struct {
+	Create []string `json:"create"`
+	Read   []string `json:"read"`
+	Update []string `json:"update"`
+	Delete []string `json:"delete"`
+}
+
+// NewPhasePermissions is a Constructor for PhasePermissions that classifies a flat list of actions by their verb
+func NewPhasePermissions(actions []string) *PhasePermissions {
+	permissions := &PhasePermissions{}
+	for _, action := range actions {
+		for _, phase := range actionPhases(action) {
+			permissions.Set(phase, append(permissions.Get(phase), action))
+		}
+	}
+	return permissions
+}
+
+// Get returns the actions needed during a phase
+func (p *PhasePermissions) Get(phase Phase) []string {
+	switch phase {
+	case PhaseCreate:
+		return p.Create
+	case PhaseRead:
+		return p.Read
+	case PhaseUpdate:
+		return p.Update
+	case PhaseDelete:
+		return p.Delete
+	}
+	return nil
+}
+
+// Set replaces the actions needed during a phase
+func (p *PhasePermissions) Set(phase Phase, actions []string) {
+	switch phase {
+	case PhaseCreate:
+		p.Create = actions
+	case PhaseRead:
+		p.Read = actions
+	case PhaseUpdate:
+		p.Update = actions
+	case PhaseDelete:
+		p.Delete = actions
+	}
+}
+
+// ForPhases returns the sorted union of the actions needed during any of the given phases
+func (p *PhasePermissions) ForPhases(phases []Phase) []string {
+	actionsSet := make(map[string]bool)
+	for _, phase := range phases {
+		for _, action := range p.Get(phase) {
+			actionsSet[action] = true
+		}
+	}
+	actionsList := make([]string, 0, len(actionsSet))
+	for action := range actionsSet {
+		actionsList = append(actionsList, action)
+	}
+	sort.Strings(actionsList)
+	return actionsList
+}
+
+// All returns the sorted union of the actions needed during every phase
+func (p *PhasePermissions) All() []string {
+	return p.ForPhases(AllPhases)
+}
@@ -0,0 +1,166 @@
+package policymaker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	tfplanManifestFilename = "terraform-plan.manifest.json"
+	tfLockFilename         = ".terraform.lock.hcl"
+)
+
+// planManifest records what the cached terraform-plan.json was generated from
+type planManifest struct {
+	// Hash covers the configuration files, the lock file and the arguments terraform was run with
+	Hash string `json:"hash"`
+	// Files are relative to the configuration directory
+	Files []string `json:"files"`
+}
+
+/*
+newPlanManifest hashes every .tf and .tfvars file under Path and under the directories of the local
+child modules, which may be outside of Path (e.g. ../modules/network), along with the lock file,
+any var files outside of Path, and the arguments the plan is made with.
+*/
+func (p *PlanParser) newPlanManifest() (*planManifest, error) {
+	root, err := filepath.Abs(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{root}
+	// a configuration that cannot be parsed is left for terraform to report
+	configParser := NewConfigParser(root)
+	configParser.quiet = true
+	if moduleDirs, err := configParser.ModuleDirs(context.Background()); err == nil {
+		dirs = append(dirs, moduleDirs...)
+	}
+	filesSet := make(map[string]bool)
+	for _, dir := range dirs {
+		// modules inside Path are walked with it
+		if dir != root && isWithin(root, dir) {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// providers and modules downloaded by terraform init are covered by the lock file and sources
+			if info.IsDir() && info.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() && isConfigurationFile(info.Name()) {
+				filesSet[path] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, varFile := range p.VarFiles {
+		if !filepath.IsAbs(varFile) {
+			varFile = filepath.Join(root, varFile)
+		}
+		filesSet[filepath.Clean(varFile)] = true
+	}
+	paths := make([]string, 0, len(filesSet))
+	for path := range filesSet {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	manifest := &planManifest{}
+	hash := sha256.New()
+	for _, path := range paths {
+		dat, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+		manifest.Files = append(manifest.Files, rel)
+		fileHash := sha256.Sum256(dat)
+		hash.Write([]byte(rel + "\x00" + hex.EncodeToString(fileHash[:]) + "\n"))
+	}
+	args := append([]string{"workspace=" + p.Workspace}, p.InitArgs...)
+	args = append(args, p.planArgs()...)
+	hash.Write([]byte(strings.Join(args, "\x00")))
+	manifest.Hash = hex.EncodeToString(hash.Sum(nil))
+	return manifest, nil
+}
+
+/*
+isPlanStale tells whether the cached plan has to be made again, and why: because Refresh is set,
+there is no plan or manifest, or the configuration has changed since the plan was made
+*/
+func (p *PlanParser) isPlanStale() (bool, string, error) {
+	if p.Refresh {
+		return true, "Refreshing plan", nil
+	}
+	if !exists(filepath.Join(p.Path, tfplanJSONFilename)) {
+		return true, "Plan does not exist", nil
+	}
+	cached := readPlanManifest(filepath.Join(p.Path, tfplanManifestFilename))
+	if cached == nil {
+		return true, "Plan has no manifest", nil
+	}
+	current, err := p.newPlanManifest()
+	if err != nil {
+		return false, "", err
+	}
+	if current.Hash != cached.Hash {
+		return true, "Configuration has changed since the plan was made", nil
+	}
+	return false, "", nil
+}
+
+// helper function that tells whether dir is root or inside it
+func isWithin(root string, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// helper function that tells whether a file can change the outcome of terraform plan
+func isConfigurationFile(name string) bool {
+	if name == tfLockFilename {
+		return true
+	}
+	for _, ext := range []string{".tf", ".tf.json", ".tfvars", ".tfvars.json"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function that reads the manifest of the cached plan, or returns nil if there is none
+func readPlanManifest(path string) *planManifest {
+	dat, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	manifest := &planManifest{}
+	if err := json.Unmarshal(dat, manifest); err != nil {
+		return nil
+	}
+	return manifest
+}
+
+func writePlanManifest(path string, manifest *planManifest) error {
+	dat, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, dat, 0644)
+}
@@ -0,0 +1,99 @@
+package policymaker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// helper function that writes files, given by their path relative to dir, creating their directories
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPlanManifestIncludesModulesOutsideOfPath(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"live/main.tf":             `module "queue" { source = "../modules/queue" }`,
+		"live/prod.tfvars":         `name = "prod"`,
+		"live/modules/a/main.tf":   `resource "aws_sns_topic" "t" {}`,
+		"modules/queue/main.tf":    `module "policy" { source = "../policy" }`,
+		"modules/policy/main.tf":   `resource "aws_sqs_queue_policy" "p" {}`,
+		"modules/unused/main.tf":   `resource "aws_sqs_queue" "q" {}`,
+		"live/.terraform/x/foo.tf": `resource "aws_sqs_queue" "q" {}`,
+	})
+	p := NewPlanParser(filepath.Join(dir, "live"))
+	manifest, err := p.newPlanManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main.tf", "modules/a/main.tf", "prod.tfvars", "../modules/policy/main.tf", "../modules/queue/main.tf"}
+	if !reflect.DeepEqual(manifest.Files, want) {
+		t.Errorf("got files %v, want %v", manifest.Files, want)
+	}
+
+	cases := []struct {
+		name  string
+		file  string
+		stale bool
+	}{
+		{"module outside of path", "modules/queue/main.tf", true},
+		{"module of a module outside of path", "modules/policy/main.tf", true},
+		{"module that is not used", "modules/unused/main.tf", false},
+		{"module downloaded by terraform init", "live/.terraform/x/foo.tf", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			before, err := p.newPlanManifest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dat, err := ioutil.ReadFile(filepath.Join(dir, c.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeFiles(t, dir, map[string]string{c.file: string(dat) + "\n# changed\n"})
+			after, err := p.newPlanManifest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stale := before.Hash != after.Hash; stale != c.stale {
+				t.Errorf("got a changed hash %v, want %v", stale, c.stale)
+			}
+		})
+	}
+}
+
+func TestPlanIsStaleWhenModuleOutsideOfPathChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"live/main.tf":               `module "queue" { source = "../modules/queue" }`,
+		"live/" + tfplanJSONFilename: `{}`,
+		"modules/queue/main.tf":      `resource "aws_sqs_queue" "q" {}`,
+	})
+	p := NewPlanParser(filepath.Join(dir, "live"))
+	manifest, err := p.newPlanManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writePlanManifest(filepath.Join(dir, "live", tfplanManifestFilename), manifest); err != nil {
+		t.Fatal(err)
+	}
+	if stale, reason, err := p.isPlanStale(); err != nil || stale {
+		t.Fatalf("got a stale plan (%s, %v) before anything changed", reason, err)
+	}
+	writeFiles(t, dir, map[string]string{"modules/queue/main.tf": `resource "aws_sqs_queue" "q" { name = "changed" }`})
+	if stale, _, err := p.isPlanStale(); err != nil || !stale {
+		t.Errorf("got a plan that is not stale (%v) after its module changed", err)
+	}
+}
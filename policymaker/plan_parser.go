@@ -1,9 +1,15 @@
 package policymaker
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/tidwall/gjson"
 )
@@ -14,65 +20,397 @@ const (
 	tfplanJSONFilename   = "terraform-plan.json"
 )
 
-// PlanParser downloads and parses the source code for a given provider
+// planFileStdin is the plan file name that reads the plan from standard input
+const planFileStdin = "-"
+
+// moduleInstanceKeyRegexp matches the instance keys in a module address, e.g. [0] or ["a"]
+var moduleInstanceKeyRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
+/*
+PlanParser downloads and parses the source code for a given provider. When PlanFile is set, the
+plan is read from that file (or from Stdin if it is "-") instead of running terraform in Path.
+The working directory of the process is never changed, so several PlanParsers can be used in
+parallel on different directories.
+*/
 type PlanParser struct {
-	Path string
+	Path     string
+	PlanFile string
+	Stdin    io.Reader
+	// Verbose streams the output of terraform while it runs
+	Verbose bool
+	// Timeout limits how long each terraform command may run, or not at all if it is zero
+	Timeout time.Duration
+	// Workspace is selected before planning, unless it is empty
+	Workspace string
+	// VarFiles are passed to terraform plan as -var-file arguments, relative to Path
+	VarFiles []string
+	// Vars are passed to terraform plan as -var arguments, e.g. "region=us-east-1"
+	Vars []string
+	// InitArgs and PlanArgs are extra arguments for terraform init and terraform plan
+	InitArgs []string
+	PlanArgs []string
+	// Refresh runs terraform plan even if the cached plan is up to date
+	Refresh bool
+	plan    string
 }
 
 // NewPlanParser is the constructor for ProviderParser
 func NewPlanParser(path string) *PlanParser {
 	return &PlanParser{
-		Path: path,
+		Path:  path,
+		Stdin: os.Stdin,
+	}
+}
+
+// NewPlanFileParser is the constructor for a PlanParser that reads an existing `terraform show -json` output
+func NewPlanFileParser(planFile string) *PlanParser {
+	return &PlanParser{
+		PlanFile: planFile,
+		Stdin:    os.Stdin,
 	}
 }
 
 /*
 GetResources gets all unique resources in a plan file
 */
-func (p *PlanParser) GetResources() []*Resource {
-	plan := p.getPlanAsJSON()
+func (p *PlanParser) GetResources(ctx context.Context) ([]*Resource, error) {
+	plan, err := p.getPlanAsJSON(ctx)
+	if err != nil {
+		return nil, err
+	}
 	rootModule := gjson.Get(plan, "configuration.root_module").String()
-	resources := p.getModuleResources(rootModule)
-	return p.removeDuplicates(resources)
+	providerConfig := gjson.Get(plan, "configuration.provider_config")
+	resources := p.getModuleResources(rootModule, "", providerConfig)
+	//resources that were removed from the configuration are only in the changes, but still need to be deleted
+	resources = append(resources, p.getChangedResources(plan)...)
+	return p.removeDuplicates(resources), nil
 }
 
-func (p *PlanParser) getPlanAsJSON() string {
-	fmt.Printf("Getting plan as JSON\n")
-	// change to folder where configuration code is in
-	cwd, _ := os.Getwd()
-	os.Chdir(p.Path)
+/*
+GetPlanValues gets the planned values of every resource instance in a plan file, along with
+the region and account id (or Azure subscription) the configuration is deployed to
+*/
+func (p *PlanParser) GetPlanValues(ctx context.Context) (*PlanValues, error) {
+	plan, err := p.getPlanAsJSON(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := &PlanValues{
+		Region:         providerConfigValue(plan, awsProvider, "region"),
+		SubscriptionID: providerConfigValue(plan, azurermProvider, "subscription_id"),
+	}
+	plannedModule := gjson.Get(plan, "planned_values.root_module").String()
+	values.Resources = p.getPlannedResources(plannedModule)
+
+	// data sources read during the plan are recorded in the prior state rather than the planned values
+	priorModule := gjson.Get(plan, "prior_state.values.root_module").String()
+	for _, resource := range append(p.getPlannedResources(priorModule), values.Resources...) {
+		if resource.Type == "aws_caller_identity" && resource.Values.Get("account_id").String() != "" {
+			values.AccountID = resource.Values.Get("account_id").String()
+		}
+		if (resource.Type == "azurerm_client_config" || resource.Type == "azurerm_subscription") && values.SubscriptionID == "" {
+			values.SubscriptionID = resource.Values.Get("subscription_id").String()
+		}
+	}
+	if values.AccountID == "" {
+		allowed := gjson.Parse(providerConfigValue(plan, awsProvider, "allowed_account_ids")).Array()
+		if len(allowed) == 1 {
+			values.AccountID = allowed[0].String()
+		}
+	}
+	return values, nil
+}
+
+/*
+helper function that returns an argument of a provider configuration in a plan, e.g. the region of
+provider "aws", either set as a constant or through a variable of the root module. The provider may
+have another local name in required_providers, and its default (unaliased) configuration in the root
+module is used before any aliased one.
+*/
+func providerConfigValue(plan string, provider string, argument string) string {
+	var value, fallback string
+	gjson.Get(plan, "configuration.provider_config").ForEach(func(_, config gjson.Result) bool {
+		if config.Get("name").String() != provider && !strings.HasSuffix(config.Get("full_name").String(), "/"+provider) {
+			return true
+		}
+		expression := config.Get("expressions." + argument)
+		argumentValue := expression.Get("constant_value").Raw
+		for _, reference := range expression.Get("references").Array() {
+			if argumentValue == "" && strings.HasPrefix(reference.String(), "var.") {
+				argumentValue = gjson.Get(plan, "variables."+strings.TrimPrefix(reference.String(), "var.")+".value").Raw
+			}
+		}
+		if argumentValue == "" {
+			return true
+		}
+		if config.Get("alias").String() == "" && config.Get("module_address").String() == "" {
+			value = argumentValue
+			return false
+		}
+		if fallback == "" {
+			fallback = argumentValue
+		}
+		return true
+	})
+	if value == "" {
+		value = fallback
+	}
+	// strings are returned without their quotes, other values as JSON
+	if result := gjson.Parse(value); result.Type == gjson.String {
+		return result.String()
+	}
+	return value
+}
 
-	if !exists(tfplanJSONFilename) {
-		fmt.Printf("Plan does not exist, creating new onen")
+/*
+GetResourcePhases gets the lifecycle phases each resource in a plan file needs permissions for,
+based on the change actions in resource_changes. Data sources only ever need to be read.
+*/
+func (p *PlanParser) GetResourcePhases(ctx context.Context) (map[string][]Phase, error) {
+	plan, err := p.getPlanAsJSON(ctx)
+	if err != nil {
+		return nil, err
+	}
+	phasesMap := make(map[string]map[Phase]bool)
+	add := func(resource *Resource, phases []Phase) {
+		key := resource.ToString()
+		if phasesMap[key] == nil {
+			phasesMap[key] = make(map[Phase]bool)
+		}
+		for _, phase := range phases {
+			phasesMap[key][phase] = true
+		}
+	}
+	gjson.Get(plan, "resource_changes").ForEach(func(key, value gjson.Result) bool {
+		resource := NewResource(value.Get("type").String(), value.Get("mode").String())
+		var changeActions []string
+		for _, changeAction := range value.Get("change.actions").Array() {
+			changeActions = append(changeActions, changeAction.String())
+		}
+		add(resource, changePhases(changeActions))
+		return true
+	})
+	resources, err := p.GetResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		if resource.Mode == ModeData {
+			add(resource, []Phase{PhaseRead})
+		}
+	}
+	phasesLists := make(map[string][]Phase, len(phasesMap))
+	for key, phases := range phasesMap {
+		for _, phase := range AllPhases {
+			if phases[phase] {
+				phasesLists[key] = append(phasesLists[key], phase)
+			}
+		}
+	}
+	return phasesLists, nil
+}
+
+/*
+GetProviders gets the addresses of every provider in a plan file, both those that are configured
+in configuration.provider_config and those that are only implied by a resource
+*/
+func (p *PlanParser) GetProviders(ctx context.Context) ([]string, error) {
+	plan, err := p.getPlanAsJSON(ctx)
+	if err != nil {
+		return nil, err
+	}
+	addressesSet := make(map[string]bool)
+	providerConfig := gjson.Get(plan, "configuration.provider_config")
+	providerConfig.ForEach(func(key, value gjson.Result) bool {
+		addressesSet[providerAddress(key.String(), providerConfig)] = true
+		return true
+	})
+	resources, err := p.GetResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		if resource.Provider != "" {
+			addressesSet[resource.Provider] = true
+		}
+	}
+	return sortedKeys(addressesSet), nil
+}
+
+/*
+GetProviderVersion gets the version of a provider the plan is made with. The lock file in Path
+is preferred, since a plan only records the version constraint, which is used if it pins a single
+version. The version is empty if it cannot be determined.
+*/
+func (p *PlanParser) GetProviderVersion(ctx context.Context, provider string) (string, error) {
+	plan, err := p.getPlanAsJSON(ctx)
+	if err != nil {
+		return "", err
+	}
+	// a plan read from a file may not belong to the configuration in Path
+	if p.PlanFile == "" {
+		version, err := lockFileVersion(p.Path, provider)
+		if err != nil || version != "" {
+			return version, err
+		}
+	}
+	var version string
+	gjson.Get(plan, "configuration.provider_config").ForEach(func(key, value gjson.Result) bool {
+		if value.Get("name").String() == provider {
+			version = exactVersion(value.Get("version_constraint").String())
+		}
+		return version == ""
+	})
+	return version, nil
+}
+
+func (p *PlanParser) getPlanAsJSON(ctx context.Context) (string, error) {
+	if p.plan != "" {
+		return p.plan, nil
+	}
+	var plan string
+	var err error
+	if p.PlanFile != "" {
+		plan, err = p.readPlanFile()
+	} else {
+		plan, err = p.runPlan(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !gjson.Valid(plan) {
+		return "", &PlanError{Op: "parse plan", Path: p.planPath(), Err: fmt.Errorf("not valid JSON")}
+	}
+	p.plan = plan
+	return p.plan, nil
+}
+
+// helper function that describes where the plan is read from, for error messages
+func (p *PlanParser) planPath() string {
+	if p.PlanFile == planFileStdin {
+		return "stdin"
+	}
+	if p.PlanFile != "" {
+		return p.PlanFile
+	}
+	return filepath.Join(p.Path, tfplanJSONFilename)
+}
+
+/*
+runPlan runs terraform in Path to produce the plan in JSON format, unless it already exists
+*/
+func (p *PlanParser) runPlan(ctx context.Context) (string, error) {
+	fmt.Printf("Getting plan as JSON\n")
+	// terraform runs in the folder the configuration code is in, the process working directory is left alone
+	planJSONPath := filepath.Join(p.Path, tfplanJSONFilename)
+	if !exists(p.Path) {
+		return "", &PlanError{Op: "find configuration", Path: p.Path, Err: os.ErrNotExist}
+	}
+	manifestPath := filepath.Join(p.Path, tfplanManifestFilename)
+	stale, reason, err := p.isPlanStale()
+	if err != nil {
+		return "", &PlanError{Op: "hash configuration", Path: p.Path, Err: err}
+	}
+	if stale {
+		fmt.Printf("%s, creating new one\n", reason)
+		// a plan without a manifest is never reused, in case this run fails halfway
+		os.Remove(manifestPath)
 		// run a terraform init
-		command := "terraform init"
-		execCmd(command)
+		if _, err := p.terraform(ctx, p.Verbose, append([]string{"init"}, p.InitArgs...)...); err != nil {
+			return "", &PlanError{Op: "initialize", Path: p.Path, Err: err}
+		}
+
+		// the workspace determines which state, and so which resources, the plan is made against
+		if p.Workspace != "" {
+			if _, err := p.terraform(ctx, p.Verbose, "workspace", "select", p.Workspace); err != nil {
+				return "", &PlanError{Op: fmt.Sprintf("select workspace %s", p.Workspace), Path: p.Path, Err: err}
+			}
+		}
 
 		// run a terraform plan and save the file in a temporary file
-		command = fmt.Sprintf("terraform plan -out=%s", tfplanStdoutFilename)
-		execCmd(command)
+		if _, err := p.terraform(ctx, p.Verbose, p.planArgs()...); err != nil {
+			return "", &PlanError{Op: "plan", Path: p.Path, Err: err}
+		}
+		//clean up
+		defer os.Remove(filepath.Join(p.Path, tfplanStdoutFilename))
 
-		// convert the plan into JSON
-		command = fmt.Sprintf("terraform show -json %s > %s", tfplanStdoutFilename, tfplanJSONFilename)
-		execCmd(command)
+		// convert the plan into JSON, which is too long to be worth streaming
+		result, err := p.terraform(ctx, false, "show", "-json", tfplanStdoutFilename)
+		if err != nil {
+			return "", &PlanError{Op: "show plan", Path: p.Path, Err: err}
+		}
+		if err := ioutil.WriteFile(planJSONPath, []byte(result.Stdout), 0644); err != nil {
+			return "", &PlanError{Op: "write plan", Path: p.Path, Err: err}
+		}
 
-		//clean up
-		os.Remove(tfplanStdoutFilename)
+		// the manifest is made after terraform init, which may have created the lock file
+		manifest, err := p.newPlanManifest()
+		if err != nil {
+			return "", &PlanError{Op: "hash configuration", Path: p.Path, Err: err}
+		}
+		if err := writePlanManifest(manifestPath, manifest); err != nil {
+			return "", &PlanError{Op: "write plan manifest", Path: p.Path, Err: err}
+		}
+	}
+
+	dat, err := ioutil.ReadFile(planJSONPath)
+	if err != nil {
+		return "", &PlanError{Op: "read plan", Path: p.Path, Err: err}
 	}
+	return string(dat), nil
+}
 
-	dat, _ := ioutil.ReadFile(tfplanJSONFilename)
-	os.Chdir(cwd)
-	json := string(dat)
-	return json
+// helper function that builds the arguments of terraform plan
+func (p *PlanParser) planArgs() []string {
+	args := []string{"plan", "-out=" + tfplanStdoutFilename}
+	for _, varFile := range p.VarFiles {
+		args = append(args, "-var-file="+varFile)
+	}
+	for _, v := range p.Vars {
+		args = append(args, "-var="+v)
+	}
+	return append(args, p.PlanArgs...)
 }
 
-func (p *PlanParser) getModuleResources(module string) []*Resource {
+// helper function that runs the terraform binary with the given arguments in Path
+func (p *PlanParser) terraform(ctx context.Context, verbose bool, args ...string) (*CommandResult, error) {
+	command := NewCommand(append([]string{"terraform"}, args...)...)
+	command.Dir = p.Path
+	command.Timeout = p.Timeout
+	command.Verbose = verbose
+	return command.Run(ctx)
+}
+
+/*
+readPlanFile reads a plan that has already been converted to JSON. It does not change the
+working directory, run terraform or write any files.
+*/
+func (p *PlanParser) readPlanFile() (string, error) {
+	var dat []byte
+	var err error
+	if p.PlanFile == planFileStdin {
+		fmt.Fprintf(os.Stderr, "Reading plan JSON from stdin\n")
+		dat, err = ioutil.ReadAll(p.Stdin)
+	} else {
+		fmt.Printf("Reading plan JSON from %s\n", p.PlanFile)
+		dat, err = ioutil.ReadFile(p.PlanFile)
+	}
+	if err != nil {
+		return "", &PlanError{Op: "read plan", Path: p.planPath(), Err: err}
+	}
+	return string(dat), nil
+}
+
+func (p *PlanParser) getModuleResources(module string, modulePath string, providerConfig gjson.Result) []*Resource {
 	var resources []*Resource
 	result := gjson.Get(module, "resources")
 	result.ForEach(func(key, value gjson.Result) bool {
 		t := value.Get("type").String()
 		m := value.Get("mode").String()
-		resources = append(resources, NewResource(t, m))
+		resource := NewResource(t, m)
+		resource.Provider = providerAddress(value.Get("provider_config_key").String(), providerConfig)
+		resource.Module = modulePath
+		resources = append(resources, resource)
 		return true
 	})
 	// has more?
@@ -80,22 +418,70 @@ func (p *PlanParser) getModuleResources(module string) []*Resource {
 	if moduleCalls.Exists() {
 		//recusively call this method again for each child module
 		moduleCalls.ForEach(func(key, value gjson.Result) bool {
-			resources = append(resources, p.getModuleResources(value.Get("module").String())...)
+			childPath := "module." + key.String()
+			if modulePath != "" {
+				childPath = modulePath + "." + childPath
+			}
+			resources = append(resources, p.getModuleResources(value.Get("module").String(), childPath, providerConfig)...)
 			return true
 		})
 	}
 	return resources
 }
 
-func (p *PlanParser) removeDuplicates(resources []*Resource) []*Resource {
-	resourceSet := make(map[*Resource]bool)
-	for _, resource := range resources {
-		resourceSet[resource] = true
+/*
+providerAddress resolves the provider_config_key of a resource (e.g. "a:aws" for a provider
+inherited by module a) to the address of the provider, preferring the full registry address
+*/
+func providerAddress(configKey string, providerConfig gjson.Result) string {
+	config := providerConfig.Map()[configKey]
+	if fullName := config.Get("full_name").String(); fullName != "" {
+		return fullName
 	}
-	//convert set into slice
-	resourceList := make([]*Resource, 0, len(resourceSet))
-	for key := range resourceSet {
-		resourceList = append(resourceList, key)
+	if name := config.Get("name").String(); name != "" {
+		return name
 	}
-	return resourceList
+	parts := strings.Split(configKey, ":")
+	return strings.Split(parts[len(parts)-1], ".")[0]
+}
+
+/*
+getChangedResources lists the resources in the resource_changes of a plan. The module path of a
+change includes the instance keys (e.g. module.a[0]), which are removed to match the configuration.
+*/
+func (p *PlanParser) getChangedResources(plan string) []*Resource {
+	var resources []*Resource
+	gjson.Get(plan, "resource_changes").ForEach(func(key, value gjson.Result) bool {
+		resource := NewResource(value.Get("type").String(), value.Get("mode").String())
+		resource.Provider = value.Get("provider_name").String()
+		resource.Module = moduleInstanceKeyRegexp.ReplaceAllString(value.Get("module_address").String(), "")
+		resources = append(resources, resource)
+		return true
+	})
+	return resources
+}
+
+func (p *PlanParser) getPlannedResources(module string) []*PlannedResource {
+	var resources []*PlannedResource
+	gjson.Get(module, "resources").ForEach(func(key, value gjson.Result) bool {
+		resource := NewResource(value.Get("type").String(), value.Get("mode").String())
+		resource.Provider = value.Get("provider_name").String()
+		resource.Module = gjson.Get(module, "address").String()
+		resources = append(resources, &PlannedResource{
+			Resource: resource,
+			Address:  value.Get("address").String(),
+			Values:   value.Get("values"),
+		})
+		return true
+	})
+	//recursively call this method again for each child module
+	gjson.Get(module, "child_modules").ForEach(func(key, value gjson.Result) bool {
+		resources = append(resources, p.getPlannedResources(value.String())...)
+		return true
+	})
+	return resources
+}
+
+func (p *PlanParser) removeDuplicates(resources []*Resource) []*Resource {
+	return NewResourceSet(resources...).List()
 }
@@ -0,0 +1,282 @@
+package policymaker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetResourcesIncludesPlannedDeletes(t *testing.T) {
+	p := NewPlanFileParser("testdata/plans/planned_delete.json")
+	resources, err := p.GetResources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, resource := range resources {
+		got = append(got, resource.String())
+	}
+	want := []string{
+		"aws_sns_topic (registry.terraform.io/hashicorp/aws)",
+		"aws_sqs_queue (registry.terraform.io/hashicorp/aws)",
+		"module.queues.aws_sqs_queue (registry.terraform.io/hashicorp/aws)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestChangeAwareGrantsDeletesOfRemovedResources(t *testing.T) {
+	providerParser := NewProviderParser("", awsProvider, true)
+	providerParser.Version = "test"
+	providerParser.OutputFile = "testdata/mappings/aws.json"
+	pm := &PolicyMaker{
+		ResourceParser:  NewPlanFileParser("testdata/plans/planned_delete.json"),
+		ProviderParsers: map[string]*ProviderParser{awsProvider: providerParser},
+		ChangeAware:     true,
+	}
+	resourceActions, err := pm.GetResourceActions(context.Background(), awsProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]map[string]bool)
+	for _, r := range resourceActions {
+		got[r.Resource.String()] = make(map[string]bool)
+		for _, action := range r.Actions {
+			got[r.Resource.String()][action] = true
+		}
+	}
+	for resource, action := range map[string]string{
+		"aws_sns_topic (registry.terraform.io/hashicorp/aws)":               "sns:DeleteTopic",
+		"module.queues.aws_sqs_queue (registry.terraform.io/hashicorp/aws)": "sqs:DeleteQueue",
+	} {
+		if !got[resource][action] {
+			t.Errorf("%s: missing %s in %v", resource, action, got[resource])
+		}
+	}
+	if got["aws_sns_topic (registry.terraform.io/hashicorp/aws)"]["sns:CreateTopic"] {
+		t.Errorf("a deleted resource must not be granted create actions")
+	}
+}
+
+// helper function that puts a shell script named terraform first in the PATH for the rest of a test
+func withFakeTerraform(t *testing.T, script string) {
+	bin := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(bin, "terraform"), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+}
+
+func TestRunPlanStopsWhenCancelled(t *testing.T) {
+	// a terraform that never finishes, so only cancelling can stop the plan
+	withFakeTerraform(t, "exec sleep 30")
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_sqs_queue" "q" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewPlanParser(dir).GetResources(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the plan to be stopped, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("terraform kept running for %s after the context ended", elapsed)
+	}
+}
+
+func TestProviderConfigValue(t *testing.T) {
+	cases := []struct {
+		name     string
+		plan     string
+		argument string
+		want     string
+	}{
+		{
+			"constant",
+			`{"configuration": {"provider_config": {"aws": {"name": "aws", "expressions": {"region": {"constant_value": "us-west-2"}}}}}}`,
+			"region",
+			"us-west-2",
+		},
+		{
+			"default configuration before an aliased one",
+			`{"configuration": {"provider_config": {
+				"aws": {"name": "aws", "expressions": {"region": {"constant_value": "us-west-2"}}},
+				"aws.east": {"name": "aws", "alias": "east", "expressions": {"region": {"constant_value": "us-east-1"}}}
+			}}}`,
+			"region",
+			"us-west-2",
+		},
+		{
+			"aliased configuration when there is no default one",
+			`{"configuration": {"provider_config": {"aws.east": {"name": "aws", "alias": "east", "expressions": {"region": {"constant_value": "us-east-1"}}}}}}`,
+			"region",
+			"us-east-1",
+		},
+		{
+			"variable",
+			`{"variables": {"region": {"value": "eu-west-1"}}, "configuration": {"provider_config": {"aws": {"name": "aws", "expressions": {"region": {"references": ["var.region"]}}}}}}`,
+			"region",
+			"eu-west-1",
+		},
+		{
+			"other local name",
+			`{"configuration": {"provider_config": {"amazon": {"name": "amazon", "full_name": "registry.terraform.io/hashicorp/aws", "expressions": {"region": {"constant_value": "ap-south-1"}}}}}}`,
+			"region",
+			"ap-south-1",
+		},
+		{
+			"list",
+			`{"configuration": {"provider_config": {"aws": {"name": "aws", "expressions": {"allowed_account_ids": {"constant_value": ["123456789012"]}}}}}}`,
+			"allowed_account_ids",
+			`["123456789012"]`,
+		},
+		{
+			"not set",
+			`{"configuration": {"provider_config": {"aws": {"name": "aws", "expressions": {}}}}}`,
+			"region",
+			"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := providerConfigValue(c.plan, awsProvider, c.argument); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRunPlanSelectsWorkspaceAndPassesArguments(t *testing.T) {
+	planFile, err := filepath.Abs("testdata/plans/planned_delete.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(t.TempDir(), "terraform.log")
+	// every command is logged, and terraform show prints the plan
+	withFakeTerraform(t, fmt.Sprintf(`echo "$@" >> %q
+if [ "$1" = show ]; then cat %q; fi`, log, planFile))
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"main.tf": `resource "aws_sqs_queue" "q" {}`, "prod.tfvars": `region = "us-east-1"`} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := NewPlanParser(dir)
+	p.Workspace = "prod"
+	p.VarFiles = []string{"prod.tfvars"}
+	p.Vars = []string{"region=us-east-1"}
+	p.InitArgs = []string{"-backend-config=prod.hcl"}
+	p.PlanArgs = []string{"-refresh=false", "-target=aws_sqs_queue.q"}
+	if _, err := p.GetResources(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dat, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSpace(string(dat)), "\n")
+	want := []string{
+		"init -backend-config=prod.hcl",
+		"workspace select prod",
+		"plan -out=terraform-plan.stdout -var-file=prod.tfvars -var=region=us-east-1 -refresh=false -target=aws_sqs_queue.q",
+		"show -json terraform-plan.stdout",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got commands %q, want %q", got, want)
+	}
+}
+
+func TestRunPlanFailsWhenWorkspaceCannotBeSelected(t *testing.T) {
+	withFakeTerraform(t, `if [ "$1" = workspace ]; then echo "Workspace \"$3\" doesn't exist." >&2; exit 1; fi`)
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "aws_sqs_queue" "q" {}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlanParser(dir)
+	p.Workspace = "missing"
+	_, err := p.GetResources(context.Background())
+	var planErr *PlanError
+	if !errors.As(err, &planErr) || planErr.Op != "select workspace missing" {
+		t.Fatalf("got %v, want the workspace selection to fail", err)
+	}
+	if !strings.Contains(err.Error(), "doesn't exist") {
+		t.Errorf("the error %q does not include the stderr of terraform", err)
+	}
+}
+
+func TestReadPlanFromStdin(t *testing.T) {
+	dat, err := ioutil.ReadFile("testdata/plans/planned_delete.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPlanFileParser(planFileStdin)
+	p.Stdin = bytes.NewReader(dat)
+	resources, err := p.GetResources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 3 {
+		t.Errorf("got %d resources, want 3", len(resources))
+	}
+	// the plan is read once, so the other methods do not wait for more input
+	if _, err := p.GetPlanValues(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	p = NewPlanFileParser(planFileStdin)
+	p.Stdin = strings.NewReader("Terraform will perform the following actions")
+	_, err = p.GetResources(context.Background())
+	var planErr *PlanError
+	if !errors.As(err, &planErr) || planErr.Path != "stdin" {
+		t.Errorf("got %v, want an error about the plan on stdin", err)
+	}
+}
+
+func TestGetProvidersOfMultipleProviders(t *testing.T) {
+	p := NewPlanFileParser("testdata/plans/multiple_providers.json")
+	addresses, err := p.GetProviders(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"registry.terraform.io/hashicorp/aws",
+		"registry.terraform.io/hashicorp/azurerm",
+		"registry.terraform.io/hashicorp/google",
+	}
+	if !reflect.DeepEqual(addresses, want) {
+		t.Errorf("got %v, want %v", addresses, want)
+	}
+
+	cases := []struct {
+		provider string
+		want     []string
+	}{
+		{"", []string{awsProvider, azurermProvider, googleProvider}},
+		{googleProvider, []string{googleProvider}},
+	}
+	for _, c := range cases {
+		pm := &PolicyMaker{Provider: c.provider, ResourceParser: p}
+		got, err := pm.GetProviders(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("provider %q: got %v, want %v", c.provider, got, c.want)
+		}
+	}
+}
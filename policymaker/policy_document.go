@@ -0,0 +1,93 @@
+package policymaker
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// PolicyVersion is the current version of the IAM policy language
+const PolicyVersion = "2012-10-17"
+
+// Effect is whether a statement allows or denies access
+type Effect string
+
+// List of available effects.
+const (
+	EffectAllow Effect = "Allow"
+	EffectDeny  Effect = "Deny"
+)
+
+// PolicyDocument represents an IAM policy document
+type PolicyDocument struct {
+	Version   string       `json:"Version"`
+	ID        string       `json:"Id,omitempty"`
+	Statement []*Statement `json:"Statement"`
+}
+
+// Statement represents a single statement in an IAM policy document
+type Statement struct {
+	Sid          string     `json:"Sid,omitempty"`
+	Effect       Effect     `json:"Effect"`
+	Principal    *Principal `json:"Principal,omitempty"`
+	NotPrincipal *Principal `json:"NotPrincipal,omitempty"`
+	Action       []string   `json:"Action,omitempty"`
+	NotAction    []string   `json:"NotAction,omitempty"`
+	Resource     []string   `json:"Resource,omitempty"`
+	NotResource  []string   `json:"NotResource,omitempty"`
+	Condition    Condition  `json:"Condition,omitempty"`
+}
+
+/*
+Principal represents the principal element of a statement. A principal with Everyone set
+is serialized as "*", otherwise as an object keyed by principal type.
+*/
+type Principal struct {
+	Everyone      bool     `json:"-"`
+	AWS           []string `json:"AWS,omitempty"`
+	Service       []string `json:"Service,omitempty"`
+	Federated     []string `json:"Federated,omitempty"`
+	CanonicalUser []string `json:"CanonicalUser,omitempty"`
+}
+
+// MarshalJSON serializes the principal, using "*" for everyone
+func (p *Principal) MarshalJSON() ([]byte, error) {
+	if p.Everyone {
+		return json.Marshal("*")
+	}
+	// use an alias so this method is not called recursively
+	type principal Principal
+	return json.Marshal((*principal)(p))
+}
+
+// UnmarshalJSON parses a principal, accepting "*" for everyone
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var everyone string
+	if json.Unmarshal(data, &everyone) == nil {
+		p.Everyone = everyone == "*"
+		return nil
+	}
+	type principal Principal
+	return json.Unmarshal(data, (*principal)(p))
+}
+
+/*
+Condition represents the condition element of a statement, mapping a condition operator
+(e.g. StringEquals) to the condition keys and the values they are compared against
+*/
+type Condition map[string]map[string][]string
+
+// NewPolicyDocument is the Constructor for PolicyDocument
+func NewPolicyDocument(statements ...*Statement) *PolicyDocument {
+	return &PolicyDocument{
+		Version:   PolicyVersion,
+		Statement: statements,
+	}
+}
+
+// JSON serializes the policy document in a human readable form, IAM rejects documents without statements
+func (d *PolicyDocument) JSON() ([]byte, error) {
+	if len(d.Statement) == 0 {
+		return nil, errors.New("the policy document has no statements")
+	}
+	return json.MarshalIndent(d, "", "  ")
+}
@@ -0,0 +1,94 @@
+package policymaker
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPolicyDocumentJSONRequiresStatements(t *testing.T) {
+	if _, err := NewPolicyDocument().JSON(); err == nil {
+		t.Error("expected an error for a document without statements")
+	}
+	statement := &Statement{Effect: EffectAllow, Action: []string{"sqs:CreateQueue"}, Resource: []string{"*"}}
+	if _, err := NewPolicyDocument(statement).JSON(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGeneratePolicyDocumentWithoutActions(t *testing.T) {
+	planFile, _ := filepath.Abs("testdata/plans/planned_delete.json")
+	mappingFile, _ := filepath.Abs("testdata/mappings/aws_empty.json")
+	inTempDir(t)
+
+	// the policy of a previous run must not be left behind
+	if err := ioutil.WriteFile("aws_policy.json", []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	providerParser := NewProviderParser("", awsProvider, true)
+	providerParser.Version = "test"
+	providerParser.OutputFile = mappingFile
+	pm := &PolicyMaker{
+		Provider:        awsProvider,
+		ResourceParser:  NewPlanFileParser(planFile),
+		ProviderParsers: map[string]*ProviderParser{awsProvider: providerParser},
+		ActionCatalogue: DefaultActionCatalogue(),
+	}
+	if err := pm.GeneratePolicyDocument(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob("aws_*.json"); len(files) > 0 {
+		t.Errorf("expected no policy files, got %v", files)
+	}
+}
+
+func TestGenerateRolesWithoutActionsRemovesPreviousRoles(t *testing.T) {
+	planFile, _ := filepath.Abs("testdata/plans/planned_delete.json")
+	dat, err := ioutil.ReadFile("testdata/mappings/aws_empty.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		provider string
+		files    []string
+	}{
+		{googleProvider, []string{"google_role.json", "google_role.yaml"}},
+		{googleBetaProvider, []string{"google-beta_role.json", "google-beta_role.yaml"}},
+		{azurermProvider, []string{"azurerm_role.json"}},
+	}
+	for _, c := range cases {
+		t.Run(c.provider, func(t *testing.T) {
+			inTempDir(t)
+			mappingFile := c.provider + "_empty.json"
+			mapping := strings.Replace(string(dat), `"provider": "aws"`, `"provider": "`+c.provider+`"`, 1)
+			if err := ioutil.WriteFile(mappingFile, []byte(mapping), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// the roles of a previous run must not be left behind
+			for _, file := range c.files {
+				if err := ioutil.WriteFile(file, []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			providerParser := NewProviderParser("", c.provider, true)
+			providerParser.Version = "test"
+			providerParser.OutputFile = mappingFile
+			pm := &PolicyMaker{
+				Provider:        c.provider,
+				ResourceParser:  NewPlanFileParser(planFile),
+				ProviderParsers: map[string]*ProviderParser{c.provider: providerParser},
+				ActionCatalogue: DefaultActionCatalogue(),
+			}
+			if err := pm.GeneratePolicyDocument(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			for _, file := range c.files {
+				if exists(file) {
+					t.Errorf("%s of the previous run was left behind", file)
+				}
+			}
+		})
+	}
+}
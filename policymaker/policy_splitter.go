@@ -0,0 +1,142 @@
+package policymaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// ManagedPolicySizeLimit is the maximum number of characters in a managed policy, excluding whitespace
+	ManagedPolicySizeLimit = 6144
+	// AttachedPoliciesQuota is the default maximum number of managed policies attached to a role
+	AttachedPoliciesQuota = 10
+)
+
+// PolicySize returns the size IAM counts against the limit, which is the minified document
+func PolicySize(document *PolicyDocument) int {
+	bytes, _ := json.Marshal(document)
+	return len(bytes)
+}
+
+/*
+SplitPolicyDocument splits a policy document that is larger than maxSize into several documents.
+Statements are broken up by service, so that the actions of a service stay together where
+possible, and then packed into as few documents as will fit.
+*/
+func SplitPolicyDocument(document *PolicyDocument, maxSize int) []*PolicyDocument {
+	if PolicySize(document) <= maxSize {
+		return []*PolicyDocument{document}
+	}
+	overhead := PolicySize(NewPolicyDocument())
+	var statements []*Statement
+	for _, statement := range document.Statement {
+		for _, serviceStatement := range splitStatementByService(statement) {
+			statements = append(statements, splitStatementBySize(serviceStatement, maxSize-overhead)...)
+		}
+	}
+
+	// first fit decreasing, which keeps the number of documents close to the minimum
+	sort.SliceStable(statements, func(i, j int) bool {
+		return statementSize(statements[i]) > statementSize(statements[j])
+	})
+	var documents []*PolicyDocument
+	var sizes []int
+	for _, statement := range statements {
+		size := statementSize(statement)
+		placed := false
+		for i, document := range documents {
+			// every statement after the first needs a separating comma
+			if sizes[i]+size+1 <= maxSize {
+				document.Statement = append(document.Statement, statement)
+				sizes[i] += size + 1
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			documents = append(documents, NewPolicyDocument(statement))
+			sizes = append(sizes, overhead+size)
+		}
+	}
+	for _, document := range documents {
+		sort.SliceStable(document.Statement, func(i, j int) bool {
+			return document.Statement[i].Sid < document.Statement[j].Sid
+		})
+	}
+	return documents
+}
+
+// helper function that breaks a statement into one statement per service
+func splitStatementByService(statement *Statement) []*Statement {
+	actionsMap := make(map[string][]string)
+	var services []string
+	for _, action := range statement.Action {
+		service := strings.Split(action, ":")[0]
+		if actionsMap[service] == nil {
+			services = append(services, service)
+		}
+		actionsMap[service] = append(actionsMap[service], action)
+	}
+	if len(services) <= 1 {
+		return []*Statement{statement}
+	}
+	sort.Strings(services)
+	statements := make([]*Statement, 0, len(services))
+	for _, service := range services {
+		serviceStatement := *statement
+		serviceStatement.Sid = statement.Sid + sidSuffix(service)
+		serviceStatement.Action = actionsMap[service]
+		statements = append(statements, &serviceStatement)
+	}
+	return statements
+}
+
+// helper function that breaks the actions of a statement into parts that each fit within maxSize
+func splitStatementBySize(statement *Statement, maxSize int) []*Statement {
+	if statementSize(statement) <= maxSize || len(statement.Action) < 2 {
+		return []*Statement{statement}
+	}
+	var statements []*Statement
+	part := *statement
+	// the Sid of each part is set up front, as it counts towards the size of the part
+	part.Sid = fmt.Sprintf("%sPart%d", statement.Sid, 1)
+	part.Action = nil
+	for _, action := range statement.Action {
+		part.Action = append(part.Action, action)
+		if len(part.Action) > 1 && statementSize(&part) > maxSize {
+			last := part.Action[len(part.Action)-1]
+			part.Action = part.Action[:len(part.Action)-1]
+			full := part
+			statements = append(statements, &full)
+			part.Sid = fmt.Sprintf("%sPart%d", statement.Sid, len(statements)+1)
+			part.Action = []string{last}
+		}
+	}
+	return append(statements, &part)
+}
+
+func statementSize(statement *Statement) int {
+	bytes, _ := json.Marshal(statement)
+	return len(bytes)
+}
+
+// helper function that turns a service prefix like resource-groups into ResourceGroups for use in a Sid
+func sidSuffix(service string) string {
+	var suffix []rune
+	upper := true
+	for _, r := range service {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		suffix = append(suffix, r)
+	}
+	return string(suffix)
+}
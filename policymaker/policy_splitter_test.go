@@ -0,0 +1,78 @@
+package policymaker
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// helper function that returns a statement with count made-up actions for each service
+func statementWithActions(sid string, count int, services ...string) *Statement {
+	statement := &Statement{Sid: sid, Effect: EffectAllow, Resource: []string{"*"}}
+	for _, service := range services {
+		for i := 0; i < count; i++ {
+			statement.Action = append(statement.Action, fmt.Sprintf("%s:DescribeSomethingNumber%d", service, i))
+		}
+	}
+	return statement
+}
+
+// helper function that returns the sorted actions of all the statements of the documents
+func documentActions(documents ...*PolicyDocument) []string {
+	var actions []string
+	for _, document := range documents {
+		for _, statement := range document.Statement {
+			actions = append(actions, statement.Action...)
+		}
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func TestSplitPolicyDocument(t *testing.T) {
+	cases := []struct {
+		name      string
+		document  *PolicyDocument
+		maxSize   int
+		documents int
+	}{
+		{"fits as it is", NewPolicyDocument(statementWithActions("Read", 5, "s3")), ManagedPolicySizeLimit, 1},
+		{"split by service", NewPolicyDocument(statementWithActions("Read", 100, "s3", "ec2", "sqs")), ManagedPolicySizeLimit, 3},
+		{"single service larger than the limit", NewPolicyDocument(statementWithActions("Read", 400, "ec2")), ManagedPolicySizeLimit, 3},
+		{"several statements", NewPolicyDocument(statementWithActions("Read", 150, "s3", "ec2"), statementWithActions("Write", 150, "s3", "iam")), 2048, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			want := documentActions(c.document)
+			documents := SplitPolicyDocument(c.document, c.maxSize)
+			if c.documents > 0 && len(documents) != c.documents {
+				t.Errorf("got %d documents, want %d", len(documents), c.documents)
+			}
+			for i, document := range documents {
+				if size := PolicySize(document); size > c.maxSize {
+					t.Errorf("document %d has size %d, more than %d", i, size, c.maxSize)
+				}
+			}
+			if got := documentActions(documents...); !reflect.DeepEqual(got, want) {
+				t.Errorf("the split documents hold %d actions, want %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestSplitPolicyDocumentSids(t *testing.T) {
+	documents := SplitPolicyDocument(NewPolicyDocument(statementWithActions("Read", 100, "s3", "resource-groups")), 2048)
+	sids := make(map[string]bool)
+	for _, document := range documents {
+		for _, statement := range document.Statement {
+			if sids[statement.Sid] {
+				t.Errorf("Sid %s is used more than once", statement.Sid)
+			}
+			sids[statement.Sid] = true
+		}
+	}
+	if !sids["ReadResourceGroupsPart1"] || !sids["ReadS3Part1"] {
+		t.Errorf("unexpected Sids %v", sids)
+	}
+}
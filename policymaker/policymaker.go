@@ -1,73 +1,338 @@
 package policymaker
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
+	"time"
+)
+
+/*
+ResourceParser lists the resources of a terraform configuration, either from a plan (PlanParser)
+or from the configuration files themselves (ConfigParser)
+*/
+type ResourceParser interface {
+	GetResources(ctx context.Context) ([]*Resource, error)
+	GetPlanValues(ctx context.Context) (*PlanValues, error)
+	GetResourcePhases(ctx context.Context) (map[string][]Phase, error)
+	GetProviders(ctx context.Context) ([]string, error)
+	GetProviderVersion(ctx context.Context, provider string) (string, error)
+}
+
+// names of the providers that are built in
+const (
+	awsProvider        = "aws"
+	googleProvider     = "google"
+	googleBetaProvider = "google-beta"
+	azurermProvider    = "azurerm"
 )
 
 // PolicyMaker is responsible for creating policy documents
 type PolicyMaker struct {
-	ProviderParser *ProviderParser
-	PlanParser     *PlanParser
+	// Provider restricts the output to a single provider, or every provider in the configuration if it is empty
+	Provider     string
+	Organization string
+	UseCache     bool
+	// Offline is passed on to the parser of each provider
+	Offline bool
+	// ProviderParsers holds the parser of each provider, keyed by provider name, created as they are needed
+	ProviderParsers map[string]*ProviderParser
+	ResourceParser  ResourceParser
+	ChangeAware     bool
+	// ActionCatalogue is used to validate generated actions and to collapse them into wildcards
+	ActionCatalogue *ActionCatalogue
+	CompressActions bool
 }
 
 // Options represents the options for creating a policymaker
 type Options struct {
+	// Provider restricts the output to a single provider, e.g. aws, or every provider in the configuration if it is empty
 	Provider     string
 	Organization string
 	UseCache     bool
 	Path         string
+	// PlanFile is an existing plan in JSON format to use instead of running terraform in Path, or "-" for stdin
+	PlanFile string
+	// Static parses the configuration files in Path instead of running terraform plan
+	Static bool
+	// ChangeAware only grants the actions needed for the changes in the plan
+	ChangeAware bool
+	// CompressActions collapses actions into wildcards that match no other actions in the catalogue
+	CompressActions bool
+	// ActionCataloguePath replaces the embedded action catalogue with a local JSON file
+	ActionCataloguePath string
+	// Verbose streams the output of terraform while it runs
+	Verbose bool
+	// CommandTimeout limits how long each terraform command may run, or not at all if it is zero
+	CommandTimeout time.Duration
+	// Workspace is selected before running terraform plan, unless it is empty
+	Workspace string
+	// VarFiles (relative to Path) and Vars (e.g. "region=us-east-1") are passed to terraform plan
+	VarFiles []string
+	Vars     []string
+	// InitArgs and PlanArgs are extra arguments for terraform init and terraform plan
+	InitArgs []string
+	PlanArgs []string
+	// RefreshPlan runs terraform plan even if the cached plan in Path is up to date
+	RefreshPlan bool
+	// ProviderVersion is the version of the provider source to build the mapping from. When empty,
+	// it is read from the lock file or the plan, and the default branch is used if that fails
+	ProviderVersion string
+	// ProviderSource is an existing checkout or archive of the provider source code to use instead of GitHub
+	ProviderSource string
+	// Offline fails instead of downloading the source of a provider that has no cached or embedded mapping
+	Offline bool
 }
 
 // NewPolicyMaker is the Constructor for PolicyMaker
-func NewPolicyMaker(o *Options) *PolicyMaker {
-	return &PolicyMaker{
-		ProviderParser: NewProviderParser(o.Organization, o.Provider, o.UseCache),
-		PlanParser:     NewPlanParser(o.Path),
+func NewPolicyMaker(o *Options) (*PolicyMaker, error) {
+	if o.PlanFile != "" && o.Static {
+		return nil, errors.New("a plan file and static parsing cannot be used together, as both replace running terraform plan")
+	}
+	planParser := NewPlanParser(o.Path)
+	planParser.Verbose = o.Verbose
+	planParser.Timeout = o.CommandTimeout
+	planParser.Workspace = o.Workspace
+	planParser.VarFiles = o.VarFiles
+	planParser.Vars = o.Vars
+	planParser.InitArgs = o.InitArgs
+	planParser.PlanArgs = o.PlanArgs
+	planParser.Refresh = o.RefreshPlan
+	var resourceParser ResourceParser = planParser
+	if o.PlanFile != "" {
+		resourceParser = NewPlanFileParser(o.PlanFile)
+	}
+	if o.Static {
+		resourceParser = NewConfigParser(o.Path)
+	}
+	p := &PolicyMaker{
+		Provider:        o.Provider,
+		Organization:    o.Organization,
+		UseCache:        o.UseCache,
+		Offline:         o.Offline,
+		ProviderParsers: make(map[string]*ProviderParser),
+		ResourceParser:  resourceParser,
+		ChangeAware:     o.ChangeAware,
+		ActionCatalogue: DefaultActionCatalogue(),
+		CompressActions: o.CompressActions,
+	}
+	if (o.ProviderVersion != "" || o.ProviderSource != "") && o.Provider == "" {
+		return nil, errors.New("a provider version or source only applies to a single provider, which has to be given as well")
+	}
+	if o.Provider != "" {
+		providerParser := NewProviderParser(o.Organization, o.Provider, o.UseCache)
+		providerParser.Offline = o.Offline
+		if o.ProviderVersion != "" {
+			providerParser.SetVersion(o.ProviderVersion)
+		}
+		if o.ProviderSource != "" {
+			source, err := NewProviderSource(o.ProviderSource)
+			if err != nil {
+				return nil, &ProviderError{Provider: o.Provider, Op: "open source", Err: err}
+			}
+			providerParser.Source = source
+		}
+		p.ProviderParsers[o.Provider] = providerParser
+	}
+	if o.ActionCataloguePath != "" {
+		catalogue, err := LoadActionCatalogue(o.ActionCataloguePath)
+		if err != nil {
+			return nil, &PolicyError{Op: fmt.Sprintf("load action catalogue %s", o.ActionCataloguePath), Err: err}
+		}
+		p.ActionCatalogue = catalogue
 	}
+	return p, nil
 }
 
 /*
-GeneratePolicyDocument Spits out a policy document based on a list of resources that are being used
+GeneratePolicyDocument Spits out a policy document for every provider in the configuration (or just
+Provider, if it is set) based on a list of resources that are being used, with the renderer registered
+for the provider: an IAM policy for aws, a custom role for google and google-beta, and a custom role
+definition for azurerm. Providers without a renderer are reported and skipped.
 */
-func (p *PolicyMaker) GeneratePolicyDocument() {
-	permissionsMap := p.ProviderParser.GetPermissionsMap()
-	resources := p.PlanParser.GetResources()
+func (p *PolicyMaker) GeneratePolicyDocument(ctx context.Context) error {
+	providers, err := p.GetProviders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(providers) == 0 {
+		return &PolicyError{Op: "find providers", Err: errors.New("the configuration does not use any providers")}
+	}
+	for _, provider := range providers {
+		renderer := NewRenderer(&RenderOptions{
+			Provider:        provider,
+			CompressActions: p.CompressActions,
+			ActionCatalogue: p.ActionCatalogue,
+		})
+		if renderer == nil {
+			fmt.Printf("######### Skipping provider %s, policies can only be generated for %s\n", provider, strings.Join(RegisteredProviders(), ", "))
+			continue
+		}
+		if err := p.generateDocuments(ctx, provider, renderer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	fmt.Println("######### New Policy")
-	permissionsSet := make(map[string]bool)
-	//add permissions to set
-	for _, resource := range resources {
-		permissions := permissionsMap[resource.ToString()]
-		for _, permission := range permissions {
-			permissionsSet[permission] = true
+/*
+GetProviders gets the names of the providers to generate policies for, e.g. aws and google, which
+is Provider if it is set, or else every provider the configuration uses
+*/
+func (p *PolicyMaker) GetProviders(ctx context.Context) ([]string, error) {
+	if p.Provider != "" {
+		return []string{p.Provider}, nil
+	}
+	addresses, err := p.ResourceParser.GetProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	namesSet := make(map[string]bool)
+	for _, address := range addresses {
+		namesSet[providerName(address)] = true
+	}
+	return sortedKeys(namesSet), nil
+}
+
+/*
+generateDocuments renders the documents of a provider and writes them to the working directory,
+replacing the output of previous runs
+*/
+func (p *PolicyMaker) generateDocuments(ctx context.Context, provider string, renderer PolicyRenderer) error {
+	actions, err := p.GetResourceActions(ctx, provider)
+	if err != nil {
+		return err
+	}
+	//clean up the output of previous runs, which may have been split into more documents
+	for _, pattern := range renderer.OutputPatterns() {
+		oldFileNames, _ := filepath.Glob(pattern)
+		for _, oldFileName := range oldFileNames {
+			os.Remove(oldFileName)
 		}
 	}
-	//convert set into slice
-	permissionsList := make([]string, 0, len(permissionsSet))
-	for permission := range permissionsSet {
-		permissionsList = append(permissionsList, `"`+permission+`"`)
-	}
-	sort.Sort(sort.StringSlice(permissionsList))
-	policyActions := "[" + strings.Join(permissionsList, ", ") + "]"
-	policy := fmt.Sprintf(`
-	{
-		"Version": "2012-10-17",
-		"Statement": [
-			{
-				"Effect": "Allow",
-				"Action": %s,
-				"Resource": "*"
+	//an empty policy or role would be rejected, so there is nothing to write
+	actionsCount := 0
+	for _, resourceActions := range actions {
+		actionsCount += len(resourceActions.Actions)
+	}
+	if actionsCount == 0 {
+		fmt.Printf("######### Warning: the resources of provider %s need no actions, no policy is created\n", provider)
+		return nil
+	}
+	planValues, err := p.ResourceParser.GetPlanValues(ctx)
+	if err != nil {
+		return err
+	}
+	documents, err := renderer.Render(actions, planValues)
+	if err != nil {
+		return err
+	}
+	for _, document := range documents {
+		os.Remove(document.FileName)
+		if err := ioutil.WriteFile(document.FileName, document.Content, 0644); err != nil {
+			return &PolicyError{Op: "write " + document.FileName, Err: err}
+		}
+		fmt.Printf("######### Policy created: %s (%s)\n", document.FileName, document.Summary)
+	}
+	return nil
+}
+
+/*
+GetResourceActions gets the actions each resource of a provider needs, from the mapping of the
+provider. When ChangeAware is set, resources only get the actions for the phases their planned
+changes go through.
+*/
+func (p *PolicyMaker) GetResourceActions(ctx context.Context, provider string) ([]*ResourceActions, error) {
+	allResources, err := p.ResourceParser.GetResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resources []*Resource
+	for _, resource := range allResources {
+		if resource.ProviderName() == provider {
+			resources = append(resources, resource)
+		}
+	}
+	providerParser, err := p.getProviderParser(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := providerParser.GetPermissionsMap(resources)
+	if err != nil {
+		return nil, err
+	}
+	var phasesMap map[string][]Phase
+	if p.ChangeAware {
+		phasesMap, err = p.ResourceParser.GetResourcePhases(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var actions []*ResourceActions
+	for _, resource := range resources {
+		resourceActions := &ResourceActions{Resource: resource}
+		if phasePermissions := mapping.Lookup(resource); phasePermissions != nil {
+			resourceActions.Actions = phasePermissions.All()
+			if p.ChangeAware {
+				resourceActions.Actions = phasePermissions.ForPhases(phasesMap[resource.ToString()])
 			}
-		]
-	}
-	`, policyActions)
-	//Write output to file
-	resourceFileName := fmt.Sprintf("%s_policy.json", p.ProviderParser.Provider)
-	os.Remove(resourceFileName)
-	ioutil.WriteFile(resourceFileName, []byte(policy), 0644)
-	fmt.Printf("######### Policy created: %s\n", resourceFileName)
+		}
+		actions = append(actions, resourceActions)
+	}
+	if missing := mapping.MissingKeys(resources); len(missing) > 0 {
+		fmt.Printf("######### Warning: the mapping of provider %s does not include %s, no actions are granted for them\n", provider, strings.Join(missing, ", "))
+	}
+	return actions, nil
+}
+
+/*
+BuildPolicyDocument builds an IAM policy document based on a list of AWS resources that are being used,
+see AWSRenderer.BuildPolicyDocument
+*/
+func (p *PolicyMaker) BuildPolicyDocument(ctx context.Context) (*PolicyDocument, error) {
+	actions, err := p.GetResourceActions(ctx, awsProvider)
+	if err != nil {
+		return nil, err
+	}
+	planValues, err := p.ResourceParser.GetPlanValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	renderer := NewAWSRenderer(&RenderOptions{
+		Provider:        awsProvider,
+		CompressActions: p.CompressActions,
+		ActionCatalogue: p.ActionCatalogue,
+	})
+	return renderer.BuildPolicyDocument(actions, planValues), nil
+}
+
+/*
+getProviderParser returns the parser of a provider, creating it if needed. Unless a version was
+given, the mapping is built from the provider version the configuration uses, once terraform init
+has selected it.
+*/
+func (p *PolicyMaker) getProviderParser(ctx context.Context, provider string) (*ProviderParser, error) {
+	providerParser := p.ProviderParsers[provider]
+	if providerParser == nil {
+		providerParser = NewProviderParser(p.Organization, provider, p.UseCache)
+		providerParser.Offline = p.Offline
+		p.ProviderParsers[provider] = providerParser
+	}
+	if providerParser.Version == "" {
+		version, err := p.ResourceParser.GetProviderVersion(ctx, provider)
+		if err != nil {
+			return nil, err
+		}
+		providerParser.SetVersion(version)
+	}
+	return providerParser, nil
+}
+
+// ValidateActions checks every action in the policy documents against the action catalogue
+func (p *PolicyMaker) ValidateActions(documents ...*PolicyDocument) *ActionValidation {
+	return (&AWSRenderer{ActionCatalogue: p.ActionCatalogue}).ValidateActions(documents...)
 }
@@ -1,194 +1,225 @@
 package policymaker
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"net/url"
-	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
-
-	"github.com/google/go-github/github"
-	getter "github.com/hashicorp/go-getter"
+	"time"
 )
 
-// ProviderParser downloads and parses the source code for a given provider
+/*
+ProviderParser provides the mapping of a given provider, which may be embedded in the binary for the
+provider version. Otherwise it downloads the source code, has Extractor build the mapping and caches the result.
+When Version is set, the source is checked out at the matching git tag, and kept apart from the other
+versions. The source is downloaded from GitHub, unless Source is set.
+*/
 type ProviderParser struct {
 	Organization string
 	Provider     string
 	Repo         string
+	Version      string
 	UseCache     bool
+	Source       ProviderSource
+	// Extractor builds the mapping from the source, by default the one registered for the provider
+	Extractor    PermissionExtractor
+	SourceDir    string
 	OutputFile   string
+	RewritesFile string
+	// Offline fails instead of downloading the source when there is no usable cached or embedded mapping
+	Offline bool
 }
 
 // NewProviderParser is the constructor for ProviderParser
 func NewProviderParser(org string, provider string, useCache bool) *ProviderParser {
-	return &ProviderParser{
+	p := &ProviderParser{
 		Organization: org,
 		Provider:     provider,
 		Repo:         fmt.Sprintf("terraform-provider-%s", provider),
 		UseCache:     useCache,
-		OutputFile:   fmt.Sprintf("%s_resouce_mapping.json", provider),
-	}
-}
-
-// GetPermissionsMap will generate and read a permissions map, if not
-func (p *ProviderParser) GetPermissionsMap() map[string][]string {
-	if !p.UseCache || !exists(p.Repo) {
-		p.downloadGithubRepo()
-		p.generatePermissionsMap()
+		Extractor:    NewExtractor(provider),
 	}
-	//if the permissions map doesn't exist, then create it
-	if !exists(p.OutputFile) {
-		p.generatePermissionsMap()
-	}
-	return p.readPermissionsMap()
+	p.SetVersion("")
+	return p
 }
 
 /*
-This method downloads all the provider source code from GitHub into the local directory
+SetVersion pins the provider source to a version, e.g. 5.31.0, which stores the source and the
+mapping under versioned names like aws_5.31.0_mapping.json. An empty version uses the default branch.
 */
-func (p *ProviderParser) downloadGithubRepo() {
-	fmt.Printf("Downloading %s repo from github\n", p.Repo)
-	client := github.NewClient(nil)
-	repository, _, err := client.Repositories.Get(context.Background(), p.Organization, p.Repo)
-	if err != nil {
-		panic(err)
+func (p *ProviderParser) SetVersion(version string) {
+	p.Version = strings.TrimPrefix(version, "v")
+	p.SourceDir = p.Repo
+	p.OutputFile = fmt.Sprintf("%s_resouce_mapping.json", p.Provider)
+	p.RewritesFile = fmt.Sprintf("%s_action_rewrites.json", p.Provider)
+	if p.Version != "" {
+		p.SourceDir = fmt.Sprintf("%s_%s", p.Repo, p.Version)
+		p.OutputFile = fmt.Sprintf("%s_%s_mapping.json", p.Provider, p.Version)
+		p.RewritesFile = fmt.Sprintf("%s_%s_action_rewrites.json", p.Provider, p.Version)
 	}
-	g := &getter.GitGetter{}
-	url, _ := url.Parse(repository.GetCloneURL())
-	g.Get(p.Repo, url)
 }
 
 /*
-Rebuild a cache for mapping terraform resource names to permissions. This should
-not be run often, it is usually enough to simply use the cached json file.
+GetPermissionsMap returns the mapping of the provider for the given resources: the cached mapping if
+there is one, or else the mapping embedded in the binary for the provider version if it holds all the
+resources, or else a mapping generated from the provider source, which is then cached. When Source is
+set the mapping is always generated from it, as the cache may have been made from another source, and
+the cache is left alone.
 */
-func (p *ProviderParser) generatePermissionsMap() {
-	fmt.Printf("Generating permissions map\n")
-	paths := p.getAllResourceFiles()
-
-	// currently only AWS is supported
-	re := regexp.MustCompile("(.*):=.*AWSClient.*")
-	permissionsMap := make(map[string][]string)
-
-	//for each resource file, find all API calls and add those permissions to the map
-	for _, path := range paths {
-		dat, _ := ioutil.ReadFile(path)
-		s := string(dat)
-		//clients will be all lines that contain ".*AWSClient.*"
-		clients := re.FindAllString(s, -1)
-
-		for _, client := range clients {
-			//fmt.Printf("%s\n", client)
-			part := strings.Split(client, ":=")[0]
-			variableName := strings.TrimSpace(part)
-			parts := strings.Split(client, "AWSClient).")
-
-			// this means its the AWS Client itself
-			if len(parts) < 2 {
-				continue
-			}
-			connectionName := parts[1]
-
-			// serviceName will be something like ec2 or s3 or lambda
-			serviceName := awsClientMap[connectionName]
-			if len(serviceName) < 1 {
-				//ignore region - false positive
-				if connectionName == "region" {
-					continue
-				}
-				//fmt.Printf("service name cannot be blank\n")
-				continue
-			}
-			searchExpression := fmt.Sprintf(`%s\.(.*?)[^(]+`, variableName)
-			searchRegex := regexp.MustCompile(searchExpression)
-			fileMatches := searchRegex.FindAllString(s, -1)
-			if len(fileMatches) < 1 {
-				fmt.Printf("No matches\n")
-				continue
-			}
-
-			//add required permissions to permission set
-			permissionsSet := make(map[string]bool)
-			for _, fileMatch := range fileMatches {
-				// trim the first part (e.g.: "conn.")
-				action := strings.Replace(fileMatch, variableName+".", "", 1)
-				permission := fmt.Sprintf("%s:%s", serviceName, action)
-				permissionsSet[permission] = true
-			}
-
-			//convert set into slice
-			permissionsList := make([]string, 0, len(permissionsSet))
-			for k := range permissionsSet {
-				permissionsList = append(permissionsList, k)
+func (p *ProviderParser) GetPermissionsMap(resources []*Resource) (*Mapping, error) {
+	if p.UseCache && p.Source == nil && exists(p.OutputFile) {
+		mapping, err := p.readPermissionsMap()
+		if err != nil {
+			return nil, &ProviderError{Provider: p.Provider, Op: "read permissions map " + p.OutputFile, Err: err}
+		}
+		return mapping, nil
+	}
+	if p.UseCache && p.Source == nil {
+		mapping, err := EmbeddedMapping(p.Provider, p.Version)
+		if err != nil {
+			return nil, &ProviderError{Provider: p.Provider, Op: "read embedded mapping", Err: err}
+		}
+		if mapping != nil {
+			missing := mapping.MissingKeys(resources)
+			if len(missing) == 0 {
+				fmt.Printf("Using the embedded mapping of provider %s %s\n", p.Provider, mapping.ProviderVersion)
+				return mapping, nil
 			}
-			resourceName := strings.Replace(filepath.Base(path), ".go", "", 1)
-			permissionsMap[resourceName] = permissionsList
+			fmt.Printf("The embedded mapping of provider %s %s does not include %s, generating the mapping from the provider source instead\n", p.Provider, mapping.ProviderVersion, strings.Join(missing, ", "))
+		} else {
+			p.printEmbeddedMappingFallback()
+		}
+	}
+	mapping, err := p.GenerateMapping()
+	if err != nil {
+		return nil, err
+	}
+	if p.Source == nil {
+		if err := p.writePermissionsMap(mapping); err != nil {
+			return nil, &ProviderError{Provider: p.Provider, Op: "write permissions map " + p.OutputFile, Err: err}
 		}
 	}
-	//Write the output to a file for caching
-	bytes, _ := json.Marshal(permissionsMap)
-	os.Remove(p.OutputFile)
-	ioutil.WriteFile(p.OutputFile, bytes, 0644)
+	if err := p.writeRewrites(); err != nil {
+		return nil, &ProviderError{Provider: p.Provider, Op: "write action rewrites " + p.RewritesFile, Err: err}
+	}
+	return mapping, nil
 }
 
 /*
-Parse the cached file into a golang map[string][]string
+GenerateMapping downloads the source of the provider and builds its mapping with Extractor, without
+caching it. This takes a while, so it is only done when there is no cached or embedded mapping, or
+to refresh the embedded mappings.
 */
-func (p *ProviderParser) readPermissionsMap() map[string][]string {
-	dat, _ := ioutil.ReadFile(p.OutputFile)
-	var tmpM map[string]interface{}
-	json.Unmarshal(dat, &tmpM)
-	permissionsMap := make(map[string][]string, len(tmpM))
-	for k, v := range tmpM {
-		tmp := v.([]interface{})
-		newV := make([]string, len(tmp))
-		for i, el := range tmp {
-			newV[i] = el.(string)
-		}
-		permissionsMap[k] = newV
+func (p *ProviderParser) GenerateMapping() (*Mapping, error) {
+	if p.Extractor == nil {
+		return nil, &ProviderError{Provider: p.Provider, Op: "generate permissions map", Err: errors.New("no permission extractor is registered for the provider")}
+	}
+	if p.Offline && p.Source == nil && (!p.UseCache || !exists(p.SourceDir)) {
+		return nil, &ProviderError{Provider: p.Provider, Op: "fetch source", Err: errors.New("downloading the provider source is disabled by -offline, give a -provider-version with an embedded mapping or a -provider-source")}
 	}
-	return permissionsMap
+	dir, err := p.source().Fetch(!p.UseCache)
+	if err != nil {
+		return nil, &ProviderError{Provider: p.Provider, Op: "fetch source", Err: err}
+	}
+	mapping, err := p.generatePermissionsMap(dir)
+	if err != nil {
+		return nil, &ProviderError{Provider: p.Provider, Op: "generate permissions map", Err: err}
+	}
+	return mapping, nil
 }
 
 /*
-This method reads the provider source code from a local folder and returns a list of all
-terraform resource and terrafrom data source files
+printEmbeddedMappingFallback explains why no embedded mapping is used. The mapping of another version
+is never used instead, even the closest one, as the resources and the actions they need change
+between versions. So the mapping is generated from the source of Version, or of the default branch
+when the version is not known.
 */
-func (p *ProviderParser) getAllResourceFiles() []string {
-	var paths []string
-	filepath.Walk(fmt.Sprint("./", p.Repo), func(path string, file os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		isVendorFile := strings.Contains(filepath.Dir(path), "vendor")
-		if !isVendorFile && p.isTerraformResourceFile(file.Name()) {
-			paths = append(paths, path)
-		}
+func (p *ProviderParser) printEmbeddedMappingFallback() {
+	embedded := strings.Join(EmbeddedMappingVersions(p.Provider), ", ")
+	if embedded == "" {
+		embedded = "none"
+	}
+	if p.Version == "" {
+		fmt.Printf("The version of provider %s is not known, so none of its embedded mappings (%s) can be used, generating the mapping from the source of the default branch instead\n", p.Provider, embedded)
+		return
+	}
+	fmt.Printf("There is no embedded mapping of provider %s %s (embedded: %s), generating the mapping from the source of v%s instead\n", p.Provider, p.Version, embedded, p.Version)
+}
+
+// helper function that returns the configured source, or the GitHub repo at Version
+func (p *ProviderParser) source() ProviderSource {
+	if p.Source != nil {
+		return p.Source
+	}
+	return &GithubSource{
+		Organization: p.Organization,
+		Repo:         p.Repo,
+		Version:      p.Version,
+		Dir:          p.SourceDir,
+	}
+}
+
+// Build the mapping of terraform resource names to permissions from the source in dir
+func (p *ProviderParser) generatePermissionsMap(dir string) (*Mapping, error) {
+	fmt.Printf("Generating permissions map\n")
+	mapping, err := p.Extractor.Extract(dir)
+	if err != nil {
+		return nil, err
+	}
+	mapping.SchemaVersion = MappingSchemaVersion
+	mapping.Provider = p.Provider
+	mapping.ProviderVersion = p.Version
+	mapping.SourceCommit = gitCommit(dir)
+	mapping.GeneratorVersion = GeneratorVersion()
+	mapping.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// Write the rewrites of the extractor next to the mapping, so the translation can be audited
+func (p *ProviderParser) writeRewrites() error {
+	reporter, ok := p.Extractor.(RewritesReporter)
+	if !ok {
 		return nil
-	})
-	return paths
+	}
+	bytes, err := json.MarshalIndent(reporter.Rewrites(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.RewritesFile, bytes, 0644)
 }
 
-//helper function for validating that a file is a terraform resource or data resource
-func (p *ProviderParser) isTerraformResourceFile(fileName string) bool {
-	//must be a go file
-	if !strings.HasSuffix(fileName, ".go") {
-		return false
+/*
+Parse and validate the cached file. Files written in an older format are migrated and written
+back in the current one, see LoadMapping.
+*/
+func (p *ProviderParser) readPermissionsMap() (*Mapping, error) {
+	dat, err := ioutil.ReadFile(p.OutputFile)
+	if err != nil {
+		return nil, err
+	}
+	mapping, version, err := LoadMapping(dat, p.Provider)
+	if err != nil {
+		return nil, err
 	}
-	//test files not allowed
-	if strings.HasSuffix(fileName, "test.go") {
-		return false
+	if version < MappingSchemaVersion {
+		mapping.ProviderVersion = p.Version
+		fmt.Printf("Migrating %s from schema version %d to %d\n", p.OutputFile, version, MappingSchemaVersion)
+		if err := p.writePermissionsMap(mapping); err != nil {
+			return nil, err
+		}
 	}
+	return mapping, nil
+}
 
-	//and have a prefix that either either a data resource of resource
-	if strings.HasPrefix(fileName, "data_source") || strings.HasPrefix(fileName, "resource") {
-		return true
+// helper function that writes a mapping to the cache file
+func (p *ProviderParser) writePermissionsMap(mapping *Mapping) error {
+	bytes, err := mapping.JSON()
+	if err != nil {
+		return err
 	}
-	//otherwise it is invalid
-	return false
+	return ioutil.WriteFile(p.OutputFile, bytes, 0644)
 }
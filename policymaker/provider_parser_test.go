@@ -0,0 +1,205 @@
+package policymaker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const awsFixtureSource = "testdata/providers/terraform-provider-aws"
+
+// helper function that runs a test in a temporary working directory, as the provider parser writes its cache there
+func inTempDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// helper function that returns the sorted actions of a phase of a resource in a mapping
+func mappedActions(t *testing.T, mapping *Mapping, key string, phase Phase) []string {
+	resource, ok := mapping.Resources[key]
+	if !ok {
+		t.Fatalf("%s is not in the mapping", key)
+	}
+	var actions []string
+	for _, action := range resource.Get(phase) {
+		actions = append(actions, action.Action)
+	}
+	return actions
+}
+
+func TestGetPermissionsMapFromSourceIgnoresCache(t *testing.T) {
+	source, err := filepath.Abs(awsFixtureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inTempDir(t)
+
+	providerParser := NewProviderParser("", awsProvider, true)
+	stale := []byte(`{"schema_version": 2, "provider": "aws", "resources": {"resource_aws_stale": {"create": [{"action": "stale:Create"}]}}}`)
+	if err := ioutil.WriteFile(providerParser.OutputFile, stale, 0644); err != nil {
+		t.Fatal(err)
+	}
+	providerParser.Source, err = NewProviderSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := providerParser.GetPermissionsMap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mapping.Resources["resource_aws_stale"]; ok {
+		t.Error("the stale cache was used instead of the provider source")
+	}
+	if got := mappedActions(t, mapping, "resource_aws_s3_bucket", PhaseDelete); !reflect.DeepEqual(got, []string{"s3:DeleteBucket"}) {
+		t.Errorf("got %v, want [s3:DeleteBucket]", got)
+	}
+	if cache, _ := ioutil.ReadFile(providerParser.OutputFile); string(cache) != string(stale) {
+		t.Error("the cache was replaced by the mapping of the provider source")
+	}
+}
+
+func TestGetPermissionsMapFromArchiveSource(t *testing.T) {
+	source, err := filepath.Abs(awsFixtureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inTempDir(t)
+	archive := "terraform-provider-aws.tar.gz"
+	if err := writeTarGz(archive, source, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	providerParser := NewProviderParser("", awsProvider, true)
+	providerParser.Source, err = NewProviderSource(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := providerParser.GetPermissionsMap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mappedActions(t, mapping, "data_source_aws_ecr_repository", PhaseRead); !reflect.DeepEqual(got, []string{"ecr:DescribeRepositories"}) {
+		t.Errorf("got %v, want [ecr:DescribeRepositories]", got)
+	}
+	if dirs, _ := filepath.Glob("terraform-provider-aws_*"); len(dirs) != 1 {
+		t.Errorf("got %v, want the archive to be extracted next to it", dirs)
+	}
+}
+
+// helper function that writes the files under dir into a gzipped tarball, inside the directory prefix if it is not empty
+func writeTarGz(archive string, dir string, prefix string) error {
+	file, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: path.Join(prefix, filepath.ToSlash(rel)), Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func TestArchiveSourceIsExtractedAgainWhenItChanges(t *testing.T) {
+	source, err := filepath.Abs("testdata/providers/terraform-provider-aws-v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inTempDir(t)
+	archive := "terraform-provider-aws.tar.gz"
+	if err := writeTarGz(archive, source, ""); err != nil {
+		t.Fatal(err)
+	}
+	archiveSource := NewArchiveSource(archive)
+	first, err := archiveSource.Fetch(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same archive is not extracted again
+	if err := ioutil.WriteFile(filepath.Join(first, "marker"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := archiveSource.Fetch(false); err != nil || again != first || !exists(filepath.Join(again, "marker")) {
+		t.Errorf("got %s (%v), want the extraction in %s to be reused", again, err, first)
+	}
+
+	changed := t.TempDir()
+	if err := copyDir(source, changed); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(changed, "CHANGELOG.md"), []byte("## 4.67.1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTarGz(archive, changed, ""); err != nil {
+		t.Fatal(err)
+	}
+	second, err := archiveSource.Fetch(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || !exists(filepath.Join(second, "CHANGELOG.md")) {
+		t.Errorf("got %s, want the changed archive to be extracted again", second)
+	}
+}
+
+func TestArchiveSourceWithSingleTopLevelDirectory(t *testing.T) {
+	source, err := filepath.Abs("testdata/providers/terraform-provider-aws-v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inTempDir(t)
+	// like the tarballs GitHub makes of a tag
+	archive := "v4.67.0.tar.gz"
+	if err := writeTarGz(archive, source, "terraform-provider-aws-4.67.0"); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := NewArchiveSource(archive).Fetch(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dir) != "terraform-provider-aws-4.67.0" {
+		t.Errorf("got %s, want the top level directory of the archive", dir)
+	}
+	mapping, err := NewAWSExtractor().Extract(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the provider.go of version 4 imports the resources by the module path in go.mod
+	if got, want := mappedActions(t, mapping, "resource_aws_sqs_queue", PhaseCreate), []string{"sqs:CreateQueue", "sqs:GetQueueAttributes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
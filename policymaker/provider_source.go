@@ -0,0 +1,217 @@
+package policymaker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/github"
+	getter "github.com/hashicorp/go-getter"
+)
+
+// archiveExtensions are the archive formats a provider source can be extracted from
+var archiveExtensions = []string{"tar.gz", "tgz", "tar.bz2", "tbz2", "tar.xz", "txz", "zip"}
+
+// ProviderSource makes the source code of a provider available in a local directory
+type ProviderSource interface {
+	// Fetch returns the directory the source is in, fetching it again if refresh is set
+	Fetch(refresh bool) (string, error)
+}
+
+// GithubSource downloads the source code of a provider from GitHub
+type GithubSource struct {
+	Organization string
+	Repo         string
+	// Version is checked out as the git tag v<Version>, or the default branch if it is empty
+	Version string
+	Dir     string
+}
+
+/*
+Fetch downloads the provider source code from GitHub into Dir, unless it has been
+downloaded before
+*/
+func (s *GithubSource) Fetch(refresh bool) (string, error) {
+	if !refresh && exists(s.Dir) {
+		return s.Dir, nil
+	}
+	fmt.Printf("Downloading %s repo from github\n", s.Repo)
+	if s.Version != "" {
+		fmt.Printf("Checking out tag v%s\n", s.Version)
+	}
+	client := github.NewClient(nil)
+	repository, _, err := client.Repositories.Get(context.Background(), s.Organization, s.Repo)
+	if err != nil {
+		return "", err
+	}
+	g := &getter.GitGetter{}
+	url, err := url.Parse(repository.GetCloneURL())
+	if err != nil {
+		return "", err
+	}
+	if s.Version != "" {
+		query := url.Query()
+		query.Set("ref", "v"+s.Version)
+		url.RawQuery = query.Encode()
+	}
+	return s.Dir, g.Get(s.Dir, url)
+}
+
+// LocalSource is an existing checkout of the provider source code, which is used as it is
+type LocalSource struct {
+	Dir string
+}
+
+// Fetch checks that the checkout exists, there is nothing to download
+func (s *LocalSource) Fetch(refresh bool) (string, error) {
+	info, err := os.Stat(s.Dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", s.Dir)
+	}
+	return s.Dir, nil
+}
+
+/*
+ArchiveSource is a tarball or zip file of the provider source code. It is extracted into a directory
+named Dir followed by the hash of the archive, so that an archive that changes under the same name is
+extracted again.
+*/
+type ArchiveSource struct {
+	Path string
+	Dir  string
+}
+
+// NewArchiveSource is the constructor for ArchiveSource, which extracts next to the working directory, named after the archive
+func NewArchiveSource(path string) *ArchiveSource {
+	name := filepath.Base(path)
+	return &ArchiveSource{
+		Path: path,
+		Dir:  strings.TrimSuffix(name, "."+archiveExtension(name)),
+	}
+}
+
+/*
+Fetch extracts the archive, unless this version of it has been extracted before. Archives like the
+ones GitHub makes hold a single directory with the source, e.g. terraform-provider-aws-5.31.0/, in
+which case that directory is returned.
+*/
+func (s *ArchiveSource) Fetch(refresh bool) (string, error) {
+	extension := archiveExtension(s.Path)
+	if extension == "" {
+		return "", fmt.Errorf("%s is not a supported archive, expected one of %s", s.Path, strings.Join(archiveExtensions, ", "))
+	}
+	hash, err := fileHash(s.Path)
+	if err != nil {
+		return "", err
+	}
+	dir := fmt.Sprintf("%s_%s", s.Dir, hash[:12])
+	if refresh || !exists(dir) {
+		fmt.Printf("Extracting %s into %s\n", s.Path, dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		if err := getter.Decompressors[extension].Decompress(dir, s.Path, true); err != nil {
+			// a partly extracted archive must not be taken for a complete one next time
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return sourceRoot(dir)
+}
+
+// helper function that returns the hex encoded sha256 hash of the content of a file
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// helper function that returns the only directory in dir if there is nothing else in it, or dir itself
+func sourceRoot(dir string) (string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+/*
+NewProviderSource picks the ProviderSource for a path on disk: a directory is used as a checkout,
+and anything else has to be an archive
+*/
+func NewProviderSource(path string) (ProviderSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &LocalSource{Dir: path}, nil
+	}
+	if archiveExtension(path) == "" {
+		return nil, fmt.Errorf("%s is neither a directory nor an archive (%s)", path, strings.Join(archiveExtensions, ", "))
+	}
+	return NewArchiveSource(path), nil
+}
+
+// helper function that returns the archive extension of a file name, or an empty string if it has none
+func archiveExtension(name string) string {
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(name), "."+extension) {
+			return extension
+		}
+	}
+	return ""
+}
+
+/*
+gitCommit returns the commit a git checkout in dir is at, read from its .git directory so that git
+does not have to be installed, or an empty string if dir is not a git checkout
+*/
+func gitCommit(dir string) string {
+	gitDir := filepath.Join(dir, ".git")
+	head, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref := strings.TrimSpace(string(head))
+	if !strings.HasPrefix(ref, "ref: ") {
+		// a detached HEAD, e.g. a checked out tag, holds the commit itself
+		return ref
+	}
+	ref = strings.TrimPrefix(ref, "ref: ")
+	if commit, err := ioutil.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(commit))
+	}
+	// refs that have not changed since cloning are only in packed-refs, as "<commit> <ref>"
+	packedRefs, err := ioutil.ReadFile(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(packedRefs), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package policymaker
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// exactVersionRegexp matches a version constraint that allows a single version, e.g. "5.31.0" or "= 5.31.0"
+var exactVersionRegexp = regexp.MustCompile(`^=?\s*v?(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)$`)
+
+// lockFileSchema describes the blocks of a .terraform.lock.hcl file that are needed
+var lockFileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "provider", LabelNames: []string{"address"}},
+	},
+}
+
+/*
+lockFileVersion reads the version of a provider (e.g. aws) selected by terraform init from the
+.terraform.lock.hcl file in dir. It returns an empty version if there is no lock file, or the
+provider is not in it.
+*/
+func lockFileVersion(dir string, provider string) (string, error) {
+	path := filepath.Join(dir, tfLockFilename)
+	if !exists(path) {
+		return "", nil
+	}
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return "", &PlanError{Op: "parse lock file", Path: path, Err: diags}
+	}
+	content, _, _ := file.Body.PartialContent(lockFileSchema)
+	for _, block := range content.Blocks {
+		// the address is the full registry address, e.g. registry.terraform.io/hashicorp/aws
+		parts := strings.Split(block.Labels[0], "/")
+		if parts[len(parts)-1] != provider {
+			continue
+		}
+		attributes, _ := block.Body.JustAttributes()
+		if version, ok := constantString(attributes["version"]); ok {
+			return version, nil
+		}
+	}
+	return "", nil
+}
+
+// exactVersion returns the version a constraint pins, or an empty version if it allows a range
+func exactVersion(constraint string) string {
+	matches := exactVersionRegexp.FindStringSubmatch(strings.TrimSpace(constraint))
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+/*
+compareVersions compares two versions like 5.31.0 numerically, returning -1, 0 or 1. Pre-release
+suffixes are ignored, and missing or non-numeric parts count as 0.
+*/
+func compareVersions(a string, b string) int {
+	partsA := versionParts(a)
+	partsB := versionParts(b)
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var partA, partB int
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		if partA != partB {
+			if partA < partB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// helper function that returns the numeric parts of a version, e.g. [5 31 0] for v5.31.0-beta1
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	return parts
+}
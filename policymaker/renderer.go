@@ -0,0 +1,83 @@
+package policymaker
+
+import (
+	"sync"
+)
+
+// ResourceActions is a resource of the configuration together with the actions its mapping grants it
+type ResourceActions struct {
+	Resource *Resource
+	Actions  []string
+}
+
+// Document is a file produced by a renderer, e.g. aws_policy.json
+type Document struct {
+	FileName string
+	Content  []byte
+	// Summary describes the content when the document is written, e.g. 42 permissions
+	Summary string
+}
+
+/*
+PolicyRenderer turns the actions the resources of a provider need into the documents that grant
+them, e.g. an IAM policy. The plan values tell where the resources will be deployed. OutputPatterns
+returns the glob patterns of every document it may write, e.g. aws_policy*.json, so that the output
+of previous runs can be removed.
+*/
+type PolicyRenderer interface {
+	Render(actions []*ResourceActions, values *PlanValues) ([]*Document, error)
+	OutputPatterns() []string
+}
+
+// RenderOptions are the settings of PolicyMaker a renderer can use
+type RenderOptions struct {
+	Provider        string
+	CompressActions bool
+	ActionCatalogue *ActionCatalogue
+}
+
+// RendererFactory creates the renderer of a provider
+type RendererFactory func(o *RenderOptions) PolicyRenderer
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = make(map[string]RendererFactory)
+)
+
+/*
+RegisterRenderer makes the renderer of a provider, e.g. aws, available to PolicyMaker. Registering
+a provider again replaces its renderer, so that other modules can add or override providers.
+*/
+func RegisterRenderer(provider string, factory RendererFactory) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	renderers[provider] = factory
+}
+
+// NewRenderer returns a new renderer for a provider, or nil if none is registered
+func NewRenderer(o *RenderOptions) PolicyRenderer {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	if factory, ok := renderers[o.Provider]; ok {
+		return factory(o)
+	}
+	return nil
+}
+
+// RegisteredProviders returns the names of the providers a renderer is registered for
+func RegisteredProviders() []string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	providers := make(map[string]bool, len(renderers))
+	for provider := range renderers {
+		providers[provider] = true
+	}
+	return sortedKeys(providers)
+}
+
+func init() {
+	RegisterRenderer(awsProvider, func(o *RenderOptions) PolicyRenderer { return NewAWSRenderer(o) })
+	RegisterRenderer(googleProvider, func(o *RenderOptions) PolicyRenderer { return NewGCPRenderer(o.Provider) })
+	RegisterRenderer(googleBetaProvider, func(o *RenderOptions) PolicyRenderer { return NewGCPRenderer(o.Provider) })
+	RegisterRenderer(azurermProvider, func(o *RenderOptions) PolicyRenderer { return NewAzureRenderer() })
+}
@@ -1,11 +1,22 @@
 package policymaker
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
 
-// Resource is a helper type
+	"github.com/tidwall/gjson"
+)
+
+/*
+Resource is a helper type. Two resources are the same if they have the same mode, type,
+provider address and module path, so a Resource value can be used as a map key.
+*/
 type Resource struct {
-	Type string
-	Mode Mode
+	Type     string
+	Mode     Mode
+	Provider string
+	Module   string
 }
 
 // Mode represents a specific resource meta type
@@ -30,3 +41,143 @@ func NewResource(t string, m string) *Resource {
 func (r *Resource) ToString() string {
 	return fmt.Sprintf(`%s_%s`, r.Mode, r.Type)
 }
+
+// ProviderName returns the name of the provider of a resource, e.g. aws for registry.terraform.io/hashicorp/aws
+func (r *Resource) ProviderName() string {
+	if r.Provider == "" {
+		// without an address the provider can only be inferred from the type prefix
+		return strings.Split(r.Type, "_")[0]
+	}
+	return providerName(r.Provider)
+}
+
+// helper function that turns a provider address (or an aliased name like aws.west) into the provider name
+func providerName(address string) string {
+	parts := strings.Split(address, "/")
+	return strings.Split(parts[len(parts)-1], ".")[0]
+}
+
+// String returns the identity of a resource, e.g. module.a.data.aws_caller_identity (aws)
+func (r *Resource) String() string {
+	address := r.Type
+	if r.Mode == ModeData {
+		address = "data." + address
+	}
+	if r.Module != "" {
+		address = r.Module + "." + address
+	}
+	if r.Provider != "" {
+		address = fmt.Sprintf("%s (%s)", address, r.Provider)
+	}
+	return address
+}
+
+/*
+helper function that normalises a provider address, so that the short addresses of older plans
+(aws), aliased names (aws.west) and legacy namespaces (registry.terraform.io/-/aws) compare equal
+to the full address registry.terraform.io/hashicorp/aws
+*/
+func normaliseProviderAddress(address string) string {
+	if address == "" {
+		return ""
+	}
+	parts := strings.Split(strings.ToLower(address), "/")
+	parts[len(parts)-1] = strings.Split(parts[len(parts)-1], ".")[0]
+	address = sourceAddress(strings.Join(parts, "/"))
+	return strings.Replace(address, "/-/", "/hashicorp/", 1)
+}
+
+/*
+ResourceSet is a set of resources compared by their identity. Provider addresses are normalised,
+as the configuration and the changes of a plan write them differently.
+*/
+type ResourceSet map[Resource]bool
+
+// NewResourceSet is a Constructor for ResourceSet
+func NewResourceSet(resources ...*Resource) ResourceSet {
+	set := make(ResourceSet, len(resources))
+	for _, resource := range resources {
+		set.Add(resource)
+	}
+	return set
+}
+
+// Add adds a resource to the set
+func (s ResourceSet) Add(resource *Resource) {
+	s[resourceSetKey(resource)] = true
+}
+
+// Contains returns whether a resource is in the set
+func (s ResourceSet) Contains(resource *Resource) bool {
+	return s[resourceSetKey(resource)]
+}
+
+// helper function that returns the identity of a resource with a normalised provider address
+func resourceSetKey(resource *Resource) Resource {
+	key := *resource
+	key.Provider = normaliseProviderAddress(key.Provider)
+	return key
+}
+
+// Union returns a new set with the resources that are in either set
+func (s ResourceSet) Union(other ResourceSet) ResourceSet {
+	union := make(ResourceSet, len(s)+len(other))
+	for resource := range s {
+		union[resource] = true
+	}
+	for resource := range other {
+		union[resource] = true
+	}
+	return union
+}
+
+// Intersection returns a new set with the resources that are in both sets
+func (s ResourceSet) Intersection(other ResourceSet) ResourceSet {
+	intersection := make(ResourceSet)
+	for resource := range s {
+		if other[resource] {
+			intersection[resource] = true
+		}
+	}
+	return intersection
+}
+
+// Difference returns a new set with the resources that are in this set but not in the other
+func (s ResourceSet) Difference(other ResourceSet) ResourceSet {
+	difference := make(ResourceSet)
+	for resource := range s {
+		if !other[resource] {
+			difference[resource] = true
+		}
+	}
+	return difference
+}
+
+// List returns the resources in the set sorted by their identity
+func (s ResourceSet) List() []*Resource {
+	resourceList := make([]*Resource, 0, len(s))
+	for resource := range s {
+		r := resource
+		resourceList = append(resourceList, &r)
+	}
+	sort.Slice(resourceList, func(i, j int) bool {
+		return resourceList[i].String() < resourceList[j].String()
+	})
+	return resourceList
+}
+
+// PlannedResource is a resource instance together with the values it is planned to have
+type PlannedResource struct {
+	*Resource
+	Address string
+	Values  gjson.Result
+}
+
+// PlanValues holds the planned resource instances and where they will be deployed
+type PlanValues struct {
+	Region    string
+	AccountID string
+	// SubscriptionID is the Azure subscription the azurerm resources are deployed to
+	SubscriptionID string
+	Resources      []*PlannedResource
+}
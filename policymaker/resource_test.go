@@ -0,0 +1,85 @@
+package policymaker
+
+import (
+	"reflect"
+	"testing"
+)
+
+// helper function that returns the identities of the resources in a set
+func resourceStrings(set ResourceSet) []string {
+	got := []string{}
+	for _, resource := range set.List() {
+		got = append(got, resource.String())
+	}
+	return got
+}
+
+func TestResourceSetOperations(t *testing.T) {
+	queue := &Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "registry.terraform.io/hashicorp/aws"}
+	topic := &Resource{Type: "aws_sns_topic", Mode: ModeManaged, Provider: "registry.terraform.io/hashicorp/aws"}
+	moduleQueue := &Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "registry.terraform.io/hashicorp/aws", Module: "module.a"}
+	dataQueue := &Resource{Type: "aws_sqs_queue", Mode: ModeData, Provider: "registry.terraform.io/hashicorp/aws"}
+	cases := []struct {
+		name string
+		got  ResourceSet
+		want []string
+	}{
+		{
+			"union",
+			NewResourceSet(queue, topic).Union(NewResourceSet(topic, moduleQueue)),
+			[]string{"aws_sns_topic (registry.terraform.io/hashicorp/aws)", "aws_sqs_queue (registry.terraform.io/hashicorp/aws)", "module.a.aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+		},
+		{
+			"intersection",
+			NewResourceSet(queue, topic, dataQueue).Intersection(NewResourceSet(topic, moduleQueue, dataQueue)),
+			[]string{"aws_sns_topic (registry.terraform.io/hashicorp/aws)", "data.aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+		},
+		{
+			"difference",
+			NewResourceSet(queue, topic, moduleQueue).Difference(NewResourceSet(topic)),
+			[]string{"aws_sqs_queue (registry.terraform.io/hashicorp/aws)", "module.a.aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+		},
+		{
+			"empty difference",
+			NewResourceSet(queue).Difference(NewResourceSet(queue)),
+			[]string{},
+		},
+		{
+			"provider addresses written differently",
+			NewResourceSet(
+				queue,
+				&Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "aws"},
+				&Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "hashicorp/aws"},
+				&Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "aws.west"},
+				&Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "registry.terraform.io/-/aws"},
+			),
+			[]string{"aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+		},
+		{
+			"providers of other namespaces are kept apart",
+			NewResourceSet(queue, &Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "example.com/acme/aws"}),
+			[]string{"aws_sqs_queue (example.com/acme/aws)", "aws_sqs_queue (registry.terraform.io/hashicorp/aws)"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := resourceStrings(c.got); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+	if !NewResourceSet(queue).Contains(&Resource{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "aws"}) {
+		t.Error("the set does not contain the resource with a short provider address")
+	}
+}
+
+func TestRemoveDuplicatesOfConfigurationAndChanges(t *testing.T) {
+	// the configuration of terraform 0.12 names the provider, while the changes use the full address
+	resources := (&PlanParser{}).removeDuplicates([]*Resource{
+		{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "aws"},
+		{Type: "aws_sqs_queue", Mode: ModeManaged, Provider: "registry.terraform.io/hashicorp/aws"},
+	})
+	if len(resources) != 1 {
+		t.Errorf("got %d resources, want 1", len(resources))
+	}
+}
@@ -0,0 +1,17 @@
+provider "aws" {
+  region = "us-west-2"
+}
+
+provider "aws" {
+  alias  = "east"
+  region = "us-east-1"
+}
+
+resource "aws_sqs_queue" "west" {
+  name = "west"
+}
+
+resource "aws_sqs_queue" "east" {
+  provider = aws.east
+  name     = "east"
+}
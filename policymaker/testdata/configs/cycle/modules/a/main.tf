@@ -0,0 +1,5 @@
+resource "aws_sqs_queue" "a" {}
+
+module "b" {
+  source = "../b"
+}
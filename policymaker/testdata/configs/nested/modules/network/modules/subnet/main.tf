@@ -0,0 +1,3 @@
+resource "aws_subnet" "this" {
+  cidr_block = "10.0.1.0/24"
+}
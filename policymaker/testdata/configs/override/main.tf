@@ -0,0 +1,7 @@
+provider "aws" {
+  region = "us-west-2"
+}
+
+module "queue" {
+  source = "./modules/fake"
+}
@@ -0,0 +1,3 @@
+resource "aws_sns_topic" "fake" {
+  name = "fake"
+}
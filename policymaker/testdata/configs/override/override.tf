@@ -0,0 +1,7 @@
+provider "aws" {
+  region = "ap-south-1"
+}
+
+module "queue" {
+  source = "./modules/real"
+}
@@ -0,0 +1,7 @@
+module "first" {
+  source = "./modules/queue"
+}
+
+module "second" {
+  source = "./modules/queue"
+}
@@ -0,0 +1,11 @@
+package conns
+
+import (
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+type AWSClient struct {
+	AccountID string
+	Region    string
+	SQSConn   *sqs.SQS
+}
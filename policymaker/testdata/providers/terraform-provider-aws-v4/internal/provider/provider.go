@@ -0,0 +1,17 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-aws/internal/service/sqs"
+)
+
+func Provider() *schema.Provider {
+	return &schema.Provider{
+		DataSourcesMap: map[string]*schema.Resource{
+			"aws_sqs_queue": sqs.DataSourceQueue(),
+		},
+		ResourcesMap: map[string]*schema.Resource{
+			"aws_sqs_queue": sqs.ResourceQueue(),
+		},
+	}
+}
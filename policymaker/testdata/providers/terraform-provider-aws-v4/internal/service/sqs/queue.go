@@ -0,0 +1,45 @@
+package sqs
+
+import (
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-aws/internal/conns"
+)
+
+func ResourceQueue() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceQueueCreate,
+		Read:   resourceQueueRead,
+		Update: resourceQueueUpdate,
+		Delete: resourceQueueDelete,
+	}
+}
+
+func resourceQueueCreate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).SQSConn
+	conn.CreateQueue(nil)
+	return resourceQueueRead(d, meta)
+}
+
+func resourceQueueRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).SQSConn
+	_, err := FindQueueAttributesByURL(conn, d.Id())
+	return err
+}
+
+func FindQueueAttributesByURL(conn *sqs.SQS, url string) (map[string]string, error) {
+	conn.GetQueueAttributes(nil)
+	return nil, nil
+}
+
+func resourceQueueUpdate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).SQSConn
+	conn.SetQueueAttributes(nil)
+	return resourceQueueRead(d, meta)
+}
+
+func resourceQueueDelete(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).SQSConn
+	conn.DeleteQueue(nil)
+	return nil
+}
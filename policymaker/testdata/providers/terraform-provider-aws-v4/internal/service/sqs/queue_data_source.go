@@ -0,0 +1,19 @@
+package sqs
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-aws/internal/conns"
+)
+
+func DataSourceQueue() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceQueueRead,
+	}
+}
+
+func dataSourceQueueRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).SQSConn
+	conn.GetQueueUrl(nil)
+	_, err := FindQueueAttributesByURL(conn, "")
+	return err
+}
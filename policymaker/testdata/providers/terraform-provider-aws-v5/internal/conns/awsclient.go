@@ -0,0 +1,25 @@
+package conns
+
+import (
+	"context"
+	"net/http"
+
+	s3_sdkv2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	ec2_sdkv1 "github.com/aws/aws-sdk-go/service/ec2"
+)
+
+type AWSClient struct {
+	httpClient *http.Client
+}
+
+func (c *AWSClient) EC2Conn(ctx context.Context) *ec2_sdkv1.EC2 {
+	return nil
+}
+
+func (c *AWSClient) S3Client(ctx context.Context) *s3_sdkv2.Client {
+	return nil
+}
+
+func (c *AWSClient) HTTPClient(ctx context.Context) *http.Client {
+	return c.httpClient
+}
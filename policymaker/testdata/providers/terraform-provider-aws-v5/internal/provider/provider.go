@@ -0,0 +1,12 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func New() *schema.Provider {
+	return &schema.Provider{
+		DataSourcesMap: make(map[string]*schema.Resource),
+		ResourcesMap:   make(map[string]*schema.Resource),
+	}
+}
@@ -0,0 +1,22 @@
+package ec2
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-aws/internal/conns"
+)
+
+func DataSourceAMI() *schema.Resource {
+	return &schema.Resource{
+		ReadWithoutTimeout: dataSourceAMIRead,
+	}
+}
+
+func dataSourceAMIRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).EC2Conn(ctx)
+	conn.DescribeImagesWithContext(ctx, nil)
+	meta.(*conns.AWSClient).HTTPClient(ctx).Do(nil)
+	return nil
+}
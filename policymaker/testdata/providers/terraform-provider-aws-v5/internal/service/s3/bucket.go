@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-provider-aws/internal/conns"
+)
+
+func ResourceBucket() *schema.Resource {
+	return &schema.Resource{
+		CreateWithoutTimeout: resourceBucketCreate,
+		ReadWithoutTimeout:   resourceBucketRead,
+		UpdateWithoutTimeout: resourceBucketUpdate,
+		DeleteWithoutTimeout: resourceBucketDelete,
+	}
+}
+
+func resourceBucketCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).S3Client(ctx)
+	conn.CreateBucket(ctx, nil)
+	return resourceBucketRead(ctx, d, meta)
+}
+
+func resourceBucketRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).S3Client(ctx)
+	findBucket(ctx, conn, d.Id())
+	pages := s3.NewListObjectVersionsPaginator(conn, nil)
+	for pages.HasMorePages() {
+		pages.NextPage(ctx)
+	}
+	return nil
+}
+
+func findBucket(ctx context.Context, conn *s3.Client, bucket string) error {
+	_, err := conn.HeadBucket(ctx, nil)
+	return err
+}
+
+func resourceBucketUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	client := meta.(*conns.AWSClient)
+	client.S3Client(ctx).PutBucketTagging(ctx, nil)
+	return resourceBucketRead(ctx, d, meta)
+}
+
+func resourceBucketDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	meta.(*conns.AWSClient).S3Client(ctx).DeleteBucket(ctx, nil)
+	return nil
+}
@@ -0,0 +1,36 @@
+package s3
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-provider-aws/internal/framework"
+)
+
+func newDirectoryBucketResource(context.Context) (resource.ResourceWithConfigure, error) {
+	r := &directoryBucketResource{}
+	return r, nil
+}
+
+type directoryBucketResource struct {
+	framework.ResourceWithConfigure
+}
+
+func (r *directoryBucketResource) Metadata(_ context.Context, request resource.MetadataRequest, response *resource.MetadataResponse) {
+	response.TypeName = "aws_s3_directory_bucket"
+}
+
+func (r *directoryBucketResource) Create(ctx context.Context, request resource.CreateRequest, response *resource.CreateResponse) {
+	conn := r.Meta().S3Client(ctx)
+	conn.CreateBucket(ctx, nil)
+}
+
+func (r *directoryBucketResource) Read(ctx context.Context, request resource.ReadRequest, response *resource.ReadResponse) {
+	conn := r.Meta().S3Client(ctx)
+	findBucket(ctx, conn, "")
+}
+
+func (r *directoryBucketResource) Delete(ctx context.Context, request resource.DeleteRequest, response *resource.DeleteResponse) {
+	awsClient := r.Meta()
+	awsClient.S3Client(ctx).DeleteBucket(ctx, nil)
+}
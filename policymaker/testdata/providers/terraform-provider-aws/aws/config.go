@@ -0,0 +1,12 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type AWSClient struct {
+	s3conn  *s3.S3
+	ec2conn *ec2.EC2
+	region  string
+}
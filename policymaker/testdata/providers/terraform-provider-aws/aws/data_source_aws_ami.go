@@ -0,0 +1,6 @@
+package aws
+
+func dataSourceAwsAmiRead(meta interface{}) {
+	conn := meta.(*AWSClient).ec2conn
+	conn.DescribeImages(nil)
+}
@@ -0,0 +1,7 @@
+package aws
+
+func dataSourceAwsEcrRepositoryRead(d interface{}, meta interface{}) error {
+	var conn = meta.(*AWSClient).ecrconn
+	conn.DescribeRepositories(nil)
+	return nil
+}
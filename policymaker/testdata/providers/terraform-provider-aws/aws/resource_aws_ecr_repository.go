@@ -0,0 +1,15 @@
+package aws
+
+func resourceAwsEcrRepositoryCreate(d interface{}, meta interface{}) error {
+	conn := meta.(*AWSClient).ecrconn
+	conn.CreateRepository(nil)
+	conn.GetAuthorizationToken(nil)
+	conn.GetBogusThing(nil)
+	return resourceAwsEcrRepositoryRead(d, meta)
+}
+
+func resourceAwsEcrRepositoryRead(d interface{}, meta interface{}) error {
+	conn := meta.(*AWSClient).ecrconn
+	conn.DescribeRepositories(nil)
+	return nil
+}
@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceAwsS3Bucket() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsS3BucketCreate,
+		Read:   resourceAwsS3BucketRead,
+		Update: resourceAwsS3BucketUpdate,
+		Delete: resourceAwsS3BucketDelete,
+	}
+}
+
+func resourceAwsS3BucketCreate(d *schema.ResourceData, meta interface{}) error {
+	s3conn := meta.(*AWSClient).s3conn
+	conn := s3conn
+	_, err := conn.CreateBucket(nil)
+	return err
+}
+
+func resourceAwsS3BucketRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).s3conn
+	conn.HeadObject(nil)
+	conn.GetBucketEncryptionWithContext(nil)
+	return readHelper(conn)
+}
+
+func readHelper(c *s3.S3) error {
+	c.
+		ListObjectVersionsPages(nil, nil)
+	return nil
+}
+
+func resourceAwsS3BucketUpdate(d *schema.ResourceData, meta interface{}) error {
+	return updateTags(meta.(*AWSClient).s3conn)
+}
+
+func updateTags(x *s3.S3) error {
+	x.PutBucketTagging(nil)
+	return nil
+}
+
+func resourceAwsS3BucketDelete(d *schema.ResourceData, meta interface{}) error {
+	meta.(*AWSClient).s3conn.DeleteBucket(nil)
+	return nil
+}
@@ -0,0 +1,11 @@
+package clients
+
+import (
+	resource "github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/services/resource/client"
+	web "github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/services/web/client"
+)
+
+type Client struct {
+	Resource *resource.Client
+	Web      *web.Client
+}
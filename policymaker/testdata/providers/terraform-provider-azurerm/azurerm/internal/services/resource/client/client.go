@@ -0,0 +1,9 @@
+package client
+
+import (
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-05-01/resources"
+)
+
+type Client struct {
+	GroupsClient *resources.GroupsClient
+}
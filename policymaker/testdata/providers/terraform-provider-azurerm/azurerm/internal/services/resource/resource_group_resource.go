@@ -0,0 +1,38 @@
+package resource
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/clients"
+)
+
+func resourceResourceGroup() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceResourceGroupCreateUpdate,
+		Read:   resourceResourceGroupRead,
+		Update: resourceResourceGroupCreateUpdate,
+		Delete: resourceResourceGroupDelete,
+	}
+}
+
+func resourceResourceGroupCreateUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Resource.GroupsClient
+	if _, err := client.CheckExistence(ctx, "name"); err != nil {
+		return err
+	}
+	if _, err := client.CreateOrUpdate(ctx, "name", parameters); err != nil {
+		return err
+	}
+	return resourceResourceGroupRead(d, meta)
+}
+
+func resourceResourceGroupRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Resource.GroupsClient
+	_, err := client.Get(ctx, "name")
+	return err
+}
+
+func resourceResourceGroupDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Resource.GroupsClient
+	future, err := client.Delete(ctx, "name")
+	return future.WaitForCompletionRef(ctx, client.Client)
+}
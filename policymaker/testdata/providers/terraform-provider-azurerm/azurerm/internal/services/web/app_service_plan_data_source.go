@@ -0,0 +1,17 @@
+package web
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/clients"
+)
+
+func dataSourceAppServicePlan() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAppServicePlanRead,
+	}
+}
+
+func dataSourceAppServicePlanRead(d *schema.ResourceData, meta interface{}) error {
+	_, err := meta.(*clients.Client).Web.AppServicePlansClient.Get(ctx, "rg", "name")
+	return err
+}
@@ -0,0 +1,10 @@
+package client
+
+import (
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2020-06-01/web"
+)
+
+type Client struct {
+	AppServicesClient     *web.AppsClient
+	AppServicePlansClient *web.AppServicePlansClient
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2020-06-01/web"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/terraform-providers/terraform-provider-azurerm/azurerm/internal/clients"
+)
+
+func resourceArmAppService() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceArmAppServiceCreate,
+		Read:   resourceArmAppServiceRead,
+		Update: resourceArmAppServiceUpdate,
+		Delete: resourceArmAppServiceDelete,
+	}
+}
+
+func resourceArmAppServiceCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Web.AppServicesClient
+	existing, err := client.Get(ctx, "rg", "name")
+	future, err := client.CreateOrUpdate(ctx, "rg", "name", site)
+	if err = future.WaitForCompletionRef(ctx, client.Client); err != nil {
+		return err
+	}
+	return resourceArmAppServiceUpdate(d, meta)
+}
+
+func resourceArmAppServiceRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Web.AppServicesClient
+	resp, err := client.Get(ctx, "rg", "name")
+	appSettings, err := client.ListApplicationSettings(ctx, "rg", "name")
+	keys, err := client.ListPublishingCredentials(ctx, "rg", "name")
+	return nil
+}
+
+func resourceArmAppServiceUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Web.AppServicesClient
+	if _, err := client.UpdateConfiguration(ctx, "rg", "name", config); err != nil {
+		return err
+	}
+	return restartAppService(client)
+}
+
+func restartAppService(client *web.AppsClient) error {
+	_, err := client.Restart(ctx, "rg", "name", nil, nil)
+	return err
+}
+
+func resourceArmAppServiceDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*clients.Client).Web.AppServicesClient
+	_, err := client.Delete(ctx, "rg", "name", nil, nil)
+	return err
+}
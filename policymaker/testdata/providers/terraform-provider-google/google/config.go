@@ -0,0 +1,11 @@
+package google
+
+import (
+	compute "google.golang.org/api/compute/v1"
+)
+
+type Config struct {
+	clientCompute *compute.Service
+}
+
+func (c *Config) NewComputeClient(userAgent string) *compute.Service { return c.clientCompute }
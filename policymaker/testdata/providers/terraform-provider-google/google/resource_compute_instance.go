@@ -0,0 +1,44 @@
+package google
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	compute "google.golang.org/api/compute/v1"
+)
+
+func resourceComputeInstance() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceComputeInstanceCreate,
+		Read:   resourceComputeInstanceRead,
+		Update: resourceComputeInstanceUpdate,
+		Delete: resourceComputeInstanceDelete,
+	}
+}
+
+func resourceComputeInstanceCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	op, err := config.NewComputeClient(userAgent).Instances.Insert(project, zone, instance).Do()
+	return resourceComputeInstanceRead(d, meta)
+}
+
+func resourceComputeInstanceRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	instance, err := config.clientCompute.Instances.Get(project, zone, name).Do()
+	return nil
+}
+
+func resourceComputeInstanceUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	client := config.NewComputeClient(userAgent)
+	setMetadata(client, d)
+	return nil
+}
+
+func setMetadata(client *compute.Service, d *schema.ResourceData) {
+	client.Instances.SetMetadata(project, zone, name, md).Do()
+}
+
+func resourceComputeInstanceDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	op, err := config.NewComputeClient(userAgent).Instances.Delete(project, zone, name).Do()
+	return nil
+}
@@ -0,0 +1,46 @@
+package google
+
+import "github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+
+func resourceComputeNetwork() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceComputeNetworkCreate,
+		Read:   resourceComputeNetworkRead,
+		Update: resourceComputeNetworkUpdate,
+		Delete: resourceComputeNetworkDelete,
+	}
+}
+
+func resourceComputeNetworkCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	url, err := replaceVars(d, config, "{{ComputeBasePath}}projects/{{project}}/global/networks")
+	if err != nil {
+		return err
+	}
+	res, err := sendRequestWithTimeout(config, "POST", project, url, userAgent, obj, d.Timeout(schema.TimeoutCreate))
+	_ = res
+	return resourceComputeNetworkRead(d, meta)
+}
+
+func resourceComputeNetworkRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	url, err := replaceVars(d, config, "{{ComputeBasePath}}projects/{{project}}/global/networks/{{name}}")
+	res, err := sendRequest(config, "GET", project, url, userAgent, nil)
+	return nil
+}
+
+func resourceComputeNetworkUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	url, err := replaceVars(d, config, "{{ComputeBasePath}}projects/{{project}}/global/networks/{{name}}")
+	res, err := sendRequest(config, "PATCH", project, url, userAgent, obj)
+	url, err = replaceVars(d, config, "{{ComputeBasePath}}projects/{{project}}/global/networks/{{name}}/addPeering")
+	res, err = sendRequest(config, "POST", project, url+"?x=y", userAgent, obj)
+	return resourceComputeNetworkRead(d, meta)
+}
+
+func resourceComputeNetworkDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+	url, err := replaceVars(d, config, "{{ComputeBasePath}}projects/{{project}}/global/networks/{{name}}")
+	res, err := sendRequest(config, "DELETE", project, url, userAgent, nil)
+	return nil
+}
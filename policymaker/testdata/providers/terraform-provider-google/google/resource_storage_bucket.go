@@ -0,0 +1,39 @@
+package google
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-provider-google/google/tpgresource"
+	transport_tpg "github.com/hashicorp/terraform-provider-google/google/transport"
+)
+
+func ResourceStorageBucket() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceStorageBucketCreate,
+		ReadContext:   resourceStorageBucketRead,
+		DeleteContext: resourceStorageBucketDelete,
+	}
+}
+
+func resourceStorageBucketCreate(d *schema.ResourceData, meta interface{}) error {
+	url, err := tpgresource.ReplaceVars(d, config, "{{StorageBasePath}}b?project={{project}}")
+	res, err := transport_tpg.SendRequest(transport_tpg.SendRequestOptions{
+		Config: config,
+		Method: "POST",
+		RawURL: url,
+	})
+	url, err = tpgresource.ReplaceVars(d, config, "{{StorageBasePath}}b/{{name}}/iam")
+	res, err = transport_tpg.SendRequest(transport_tpg.SendRequestOptions{Config: config, Method: "PUT", RawURL: url})
+	return nil
+}
+
+func resourceStorageBucketRead(d *schema.ResourceData, meta interface{}) error {
+	url, err := tpgresource.ReplaceVars(d, config, "{{StorageBasePath}}b/{{name}}")
+	res, err := transport_tpg.SendRequest(transport_tpg.SendRequestOptions{Config: config, Method: "GET", RawURL: url})
+	return nil
+}
+
+func resourceStorageBucketDelete(d *schema.ResourceData, meta interface{}) error {
+	url, err := tpgresource.ReplaceVars(d, config, "{{StorageBasePath}}b/{{name}}")
+	res, err := transport_tpg.SendRequest(transport_tpg.SendRequestOptions{Config: config, Method: "DELETE", RawURL: url})
+	return nil
+}
@@ -0,0 +1,22 @@
+package policymaker
+
+import (
+	"runtime/debug"
+)
+
+/*
+Version is the version of terraform-policymaker written into the mapping files it generates. It
+can be set when building, with -ldflags "-X github.com/scottwinkler/terraform-policymaker/policymaker.Version=v1.0.0"
+*/
+var Version = ""
+
+// GeneratorVersion returns Version, or else the module version the binary was built from, e.g. (devel)
+func GeneratorVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+
+	"github.com/scottwinkler/terraform-policymaker/policymaker"
+)
+
+/*
+updateCatalogue is the update-catalogue command, which snapshots every service of the AWS service
+authorization reference into an action catalogue, e.g. to refresh the embedded one with its default
+-output
+*/
+func updateCatalogue(args []string) error {
+	flags := flag.NewFlagSet("update-catalogue", flag.ExitOnError)
+	sourcePtr := flags.String("source", policymaker.ServiceReferenceURL, "the url of the AWS service authorization reference in JSON")
+	outputPtr := flags.String("output", "policymaker/catalogue/aws_service_reference.json", "the file to write the action catalogue to")
+	flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	catalogue, err := policymaker.FetchActionCatalogue(ctx, *sourcePtr)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(catalogue, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*outputPtr, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("######### Action catalogue created: %s (version %s, %d services)\n", *outputPtr, catalogue.Version, len(catalogue.Services))
+	return nil
+}